@@ -29,5 +29,5 @@ func init() {
 	// Set the DynamoDB client
 	dbClient = dynamodb.NewFromConfig(cfg)
 	// Create the tables if they don't exist
-	datastore.EnsureBothLocalTablesExist(dbClient)
+	datastore.EnsureLocalTablesExist(dbClient)
 }
@@ -4,10 +4,16 @@ import "github.com/noahkawaguchi/verdict/backend/internal/models"
 
 // mockDatastore implements the datastore interface for testing purposes.
 type mockDatastore struct {
-	PutPollMock    func(poll *models.Poll) error
-	GetPollMock    func(pollID string) (*models.Poll, error)
-	PutBallotMock  func(ballot *models.Ballot) error
-	GetBallotsMock func(pollID string) ([]*models.Ballot, error)
+	PutPollMock      func(poll *models.Poll) error
+	GetPollMock      func(pollID string) (*models.Poll, error)
+	EditPollMock     func(poll *models.Poll) (bool, error)
+	SchedulePollMock func(poll *models.Poll) (bool, error)
+	DeletePollMock   func(pollID string) error
+	PutBallotMock    func(ballot *models.Ballot) error
+	PutBallotsMock   func(ballots []*models.Ballot) error
+	GetBallotsMock   func(pollID string) ([]*models.Ballot, error)
+	GetTallyMock     func(pollID string) (*models.Tally, error)
+	PutTallyMock     func(tally *models.Tally) error
 }
 
 func (m *mockDatastore) PutPoll(poll *models.Poll) error {
@@ -24,6 +30,27 @@ func (m *mockDatastore) GetPoll(pollID string) (*models.Poll, error) {
 	return nil, nil
 }
 
+func (m *mockDatastore) EditPoll(poll *models.Poll) (bool, error) {
+	if m.EditPollMock != nil {
+		return m.EditPollMock(poll)
+	}
+	return true, nil
+}
+
+func (m *mockDatastore) SchedulePoll(poll *models.Poll) (bool, error) {
+	if m.SchedulePollMock != nil {
+		return m.SchedulePollMock(poll)
+	}
+	return true, nil
+}
+
+func (m *mockDatastore) DeletePoll(pollID string) error {
+	if m.DeletePollMock != nil {
+		return m.DeletePollMock(pollID)
+	}
+	return nil
+}
+
 func (m *mockDatastore) PutBallot(ballot *models.Ballot) error {
 	if m.PutBallotMock != nil {
 		return m.PutBallotMock(ballot)
@@ -31,9 +58,30 @@ func (m *mockDatastore) PutBallot(ballot *models.Ballot) error {
 	return nil
 }
 
+func (m *mockDatastore) PutBallots(ballots []*models.Ballot) error {
+	if m.PutBallotsMock != nil {
+		return m.PutBallotsMock(ballots)
+	}
+	return nil
+}
+
 func (m *mockDatastore) GetBallots(pollID string) ([]*models.Ballot, error) {
 	if m.GetBallotsMock != nil {
 		return m.GetBallotsMock(pollID)
 	}
 	return nil, nil
 }
+
+func (m *mockDatastore) GetTally(pollID string) (*models.Tally, error) {
+	if m.GetTallyMock != nil {
+		return m.GetTallyMock(pollID)
+	}
+	return nil, nil
+}
+
+func (m *mockDatastore) PutTally(tally *models.Tally) error {
+	if m.PutTallyMock != nil {
+		return m.PutTallyMock(tally)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"maps"
 	"net/http"
 	"os"
@@ -8,6 +9,8 @@ import (
 	"strings"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
 )
 
 // getShortPath extracts the base path without parameters for routing purposes.
@@ -20,10 +23,125 @@ func getShortPath(longPath string) string {
 	return "default"
 }
 
+// getPollAndBallots retrieves the poll specified by the poll ID path parameter and all of its
+// ballots, which come from the poll's tally snapshot and so have no user IDs. If either cannot be
+// retrieved, it returns the error response to send instead.
+func (h *handler) getPollAndBallots() (
+	*models.Poll, []*models.Ballot, *events.APIGatewayProxyResponse,
+) {
+	// Check for the poll ID
+	pollID := h.req.PathParameters["pollId"]
+	if pollID == "" {
+		return nil, nil, utils.Ref(resp400("missing poll ID"))
+	}
+	// Get the poll from the database
+	poll, err := h.store.GetPoll(pollID)
+	if err != nil {
+		return nil, nil, utils.Ref(resp500("failed to get the poll from the database"))
+	}
+	// Handle nonexistent polls
+	if err = poll.Validate(); err != nil {
+		return nil, nil, utils.Ref(resp404("no poll found for the specified ID"))
+	}
+	// Get the poll's ballots from its tally snapshot
+	tally, err := h.getTally(poll)
+	if err != nil {
+		return nil, nil,
+			utils.Ref(resp500("failed to get the poll's ballots from the database"))
+	}
+	ballots, err := tally.Ballots()
+	if err != nil {
+		return nil, nil, utils.Ref(resp500("failed to read the poll's tally"))
+	}
+	// Handle the case where no ballots are found
+	if len(ballots) == 0 {
+		return nil, nil, utils.Ref(resp404("no ballots found for the specified poll"))
+	}
+	return poll, ballots, nil
+}
+
+// getPollAsAdmin retrieves the poll specified by the poll ID path parameter, but only if the
+// request is authorized with the poll's admin token as a bearer token. If the poll cannot be
+// retrieved or the token is missing or wrong, it returns the error response to send instead.
+func (h *handler) getPollAsAdmin() (*models.Poll, *events.APIGatewayProxyResponse) {
+	// Check for the poll ID
+	pollID := h.req.PathParameters["pollId"]
+	if pollID == "" {
+		return nil, utils.Ref(resp400("missing poll ID"))
+	}
+	// Header names are case-insensitive
+	var token string
+	for name, value := range h.req.Headers {
+		if strings.EqualFold(name, "Authorization") {
+			token, _ = strings.CutPrefix(value, "Bearer ")
+		}
+	}
+	if token == "" {
+		return nil, utils.Ref(resp401("missing admin token"))
+	}
+	// Get the poll from the database
+	poll, err := h.store.GetPoll(pollID)
+	if err != nil {
+		return nil, utils.Ref(resp500("failed to get the poll from the database"))
+	}
+	// Handle nonexistent polls
+	if err = poll.Validate(); err != nil {
+		return nil, utils.Ref(resp404("no poll found for the specified ID"))
+	}
+	if !poll.IsAdmin(token) {
+		return nil, utils.Ref(resp403("invalid admin token"))
+	}
+	return poll, nil
+}
+
+// updatePoll stores the changes to a poll using the provided update and responds with the
+// updated poll, or with the provided response if the update's condition failed.
+func (h *handler) updatePoll(
+	poll *models.Poll,
+	update func(poll *models.Poll) (bool, error),
+	failed events.APIGatewayProxyResponse,
+) events.APIGatewayProxyResponse {
+	updated, err := update(poll)
+	if err != nil {
+		return resp500("failed to update the poll in the database")
+	}
+	if !updated {
+		return failed
+	}
+	body, err := json.Marshal(poll)
+	if err != nil {
+		return resp500("failed to marshal response")
+	}
+	return resp200(string(body))
+}
+
+// getTally retrieves the poll's tally snapshot. Only if the snapshot is missing or its version
+// does not match the poll's ballot version are all of the ballots read to rebuild it.
+func (h *handler) getTally(poll *models.Poll) (*models.Tally, error) {
+	tally, err := h.store.GetTally(poll.ID())
+	if err != nil {
+		return nil, err
+	}
+	if tally != nil && tally.Version() == poll.BallotVersion() {
+		return tally, nil
+	}
+	ballots, err := h.store.GetBallots(poll.ID())
+	if err != nil {
+		return nil, err
+	}
+	poll.Weigh(ballots...)
+	// The ballots were read after the poll, so the snapshot may include ballots newer than its
+	// version, which only causes another rebuild
+	tally = models.TallyOf(poll, ballots)
+	// A snapshot that fails to be stored is rebuilt next time, so the ballots can still be used
+	_ = h.store.PutTally(tally)
+	return tally, nil
+}
+
 var defaultHeaders = map[string]string{
 	"Content-Type":                 "application/json",
 	"Access-Control-Allow-Origin":  os.Getenv("FRONTEND_URL"),
-	"Access-Control-Allow-Methods": "OPTIONS,GET,POST",
+	"Access-Control-Allow-Methods": "OPTIONS,GET,POST,PUT,DELETE",
 	"Access-Control-Allow-Headers": "Content-Type,Authorization",
 }
 
@@ -36,6 +154,17 @@ func resp200(body string) events.APIGatewayProxyResponse {
 	}
 }
 
+// resp200Text creates a 200 OK HTTP response with a plain text body, such as an exported file.
+func resp200Text(body string) events.APIGatewayProxyResponse {
+	textHeaders := maps.Clone(defaultHeaders)
+	textHeaders["Content-Type"] = "text/plain; charset=utf-8"
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    textHeaders,
+		Body:       body,
+	}
+}
+
 // resp201 creates a 201 Created HTTP response with the provided body.
 func resp201(body string) events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
@@ -54,6 +183,24 @@ func resp400(errMsg string) events.APIGatewayProxyResponse {
 	}
 }
 
+// resp401 creates a 401 Unauthorized HTTP response with a custom error message.
+func resp401(errMsg string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusUnauthorized,
+		Headers:    defaultHeaders,
+		Body:       `{"error":"` + errMsg + `"}`,
+	}
+}
+
+// resp403 creates a 403 Forbidden HTTP response with a custom error message.
+func resp403(errMsg string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusForbidden,
+		Headers:    defaultHeaders,
+		Body:       `{"error":"` + errMsg + `"}`,
+	}
+}
+
 // resp404 creates a 404 Not Found HTTP response with a custom error message.
 func resp404(errMsg string) events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
@@ -75,6 +222,15 @@ func resp405(receivedMethod string, allowedMethods ...string) events.APIGatewayP
 	}
 }
 
+// resp409 creates a 409 Conflict HTTP response with a custom error message.
+func resp409(errMsg string) events.APIGatewayProxyResponse {
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusConflict,
+		Headers:    defaultHeaders,
+		Body:       `{"error":"` + errMsg + `"}`,
+	}
+}
+
 // resp500 creates a 500 Internal Server Error HTTP response with a custom error message.
 func resp500(errMsg string) events.APIGatewayProxyResponse {
 	return events.APIGatewayProxyResponse{
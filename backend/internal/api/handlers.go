@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
@@ -17,12 +18,14 @@ func (h *handler) createPoll() events.APIGatewayProxyResponse {
 	if err := poll.Validate(); err != nil {
 		return resp400(err.Error())
 	}
+	// Generate the admin token before storing its hash with the poll
+	adminToken := poll.NewAdminToken()
 	// Put the poll in the database
 	if err := h.store.PutPoll(poll); err != nil {
 		return resp500("failed to put the poll in the database")
 	}
-	// Send the poll ID back in the response
-	return resp201(`{"pollId":"` + poll.ID() + `"}`)
+	// Send the poll ID and the admin token, which cannot be retrieved later, back in the response
+	return resp201(`{"pollId":"` + poll.ID() + `","adminToken":"` + adminToken + `"}`)
 }
 
 func (h *handler) getPollInfo() events.APIGatewayProxyResponse {
@@ -38,7 +41,7 @@ func (h *handler) getPollInfo() events.APIGatewayProxyResponse {
 	}
 	// Handle nonexistent polls
 	if err = poll.Validate(); err != nil {
-		return resp404("no poll found for poll ID " + pollID)
+		return resp404("no poll found for the specified ID")
 	}
 	// Marshal the response
 	body, err := json.Marshal(poll)
@@ -54,10 +57,32 @@ func (h *handler) castBallot() events.APIGatewayProxyResponse {
 	if err := json.Unmarshal([]byte(h.req.Body), &ballot); err != nil {
 		return resp400("invalid JSON")
 	}
+	// Check for the poll ID before looking up the poll
+	if ballot.PollID() == "" {
+		return resp400("poll ID cannot be empty")
+	}
+	// Get the poll from the database, since its settings determine which rank orders are valid
+	poll, err := h.store.GetPoll(ballot.PollID())
+	if err != nil {
+		return resp500("failed to get the poll from the database")
+	}
+	// Handle nonexistent polls
+	if err = poll.Validate(); err != nil {
+		return resp404("no poll found for the specified ID")
+	}
+	// Only accept ballots during the poll's voting window
+	switch poll.Status(time.Now()) {
+	case models.StatusDraft:
+		return resp403("the poll is not open for voting yet")
+	case models.StatusClosed:
+		return resp409("the poll is closed to new ballots")
+	}
 	// Validate the fields
-	if err := ballot.Validate(); err != nil {
+	if err = ballot.ValidateFor(poll); err != nil {
 		return resp400(err.Error())
 	}
+	// Weigh the ballot by the voter's weight on the voter roll before it is tallied
+	poll.Weigh(ballot)
 	// Put the ballot in the database
 	if err := h.store.PutBallot(ballot); err != nil {
 		return resp500("failed to put the ballot in the database")
@@ -67,38 +92,197 @@ func (h *handler) castBallot() events.APIGatewayProxyResponse {
 }
 
 func (h *handler) getResult() events.APIGatewayProxyResponse {
-	// Check for the poll ID
-	pollID := h.req.PathParameters["pollId"]
-	if pollID == "" {
-		return resp400("missing poll ID")
+	poll, ballots, errResp := h.getPollAndBallots()
+	if errResp != nil {
+		return *errResp
 	}
-	// Get the poll from the database
-	poll, err := h.store.GetPoll(pollID)
+	// Calculate the result using the poll's tabulation method
+	result, err := models.Tabulate(poll, ballots)
 	if err != nil {
-		return resp500("failed to get the poll from the database")
-	}
-	// Handle nonexistent polls
-	if err = poll.Validate(); err != nil {
-		return resp404("no poll found for poll ID " + pollID)
+		return resp500(err.Error())
 	}
-	// Get the poll's ballots from the database
-	ballots, err := h.store.GetBallots(pollID)
+	// Marshal the response
+	body, err := json.Marshal(result)
 	if err != nil {
-		return resp500("failed to get the poll's ballots from the database")
+		return resp500("failed to marshal response")
 	}
-	// Handle the case where no ballots are found
-	if len(ballots) == 0 {
-		return resp404("no ballots found for the specified poll")
+	return resp200(string(body))
+}
+
+func (h *handler) getPairwise() events.APIGatewayProxyResponse {
+	poll, ballots, errResp := h.getPollAndBallots()
+	if errResp != nil {
+		return *errResp
 	}
-	// Calculate the result
-	result, err := models.NewResult(poll, ballots)
+	// Calculate the head-to-head matrix
+	pairwise, err := models.NewPairwiseResult(poll, ballots)
 	if err != nil {
 		return resp500(err.Error())
 	}
 	// Marshal the response
-	body, err := json.Marshal(result)
+	body, err := json.Marshal(pairwise)
+	if err != nil {
+		return resp500("failed to marshal response")
+	}
+	return resp200(string(body))
+}
+
+func (h *handler) getWithdrawal() events.APIGatewayProxyResponse {
+	poll, ballots, errResp := h.getPollAndBallots()
+	if errResp != nil {
+		return *errResp
+	}
+	// Rerun the count without the choices in the withdraw query parameters
+	withdrawal, err := models.NewWithdrawalResult(
+		poll, ballots, h.req.MultiValueQueryStringParameters["withdraw"],
+	)
+	if err != nil {
+		return resp400(err.Error())
+	}
+	// Marshal the response
+	body, err := json.Marshal(withdrawal)
 	if err != nil {
 		return resp500("failed to marshal response")
 	}
 	return resp200(string(body))
 }
+
+func (h *handler) exportPoll() events.APIGatewayProxyResponse {
+	poll, ballots, errResp := h.getPollAndBallots()
+	if errResp != nil {
+		return *errResp
+	}
+	// Write the ballots in the requested file format
+	switch h.req.QueryStringParameters["format"] {
+	case "blt":
+		blt, err := models.FormatBLT(poll, ballots)
+		if err != nil {
+			return resp400(err.Error())
+		}
+		return resp200Text(blt)
+	case "cvr":
+		body, err := models.FormatCVR(poll, ballots, time.Now())
+		if err != nil {
+			// The report itself always marshals, so the only error is ballots it cannot hold
+			return resp400(err.Error())
+		}
+		return resp200(string(body))
+	default:
+		return resp400("unsupported export format")
+	}
+}
+
+func (h *handler) importPoll() events.APIGatewayProxyResponse {
+	// Parse the file in the requested format into a new poll and its ballots
+	var poll *models.Poll
+	var ballots []*models.Ballot
+	var err error
+	switch h.req.QueryStringParameters["format"] {
+	case "blt":
+		poll, ballots, err = models.ParseBLT(h.req.Body)
+	case "cvr":
+		poll, ballots, err = models.ParseCVR([]byte(h.req.Body))
+	default:
+		return resp400("unsupported import format")
+	}
+	if err != nil {
+		return resp400(err.Error())
+	}
+	// Put the poll with its admin token's hash and then its ballots in the database
+	adminToken := poll.NewAdminToken()
+	if err := h.store.PutPoll(poll); err != nil {
+		return resp500("failed to put the poll in the database")
+	}
+	if err := h.store.PutBallots(ballots); err != nil {
+		return resp500("failed to put the ballots in the database")
+	}
+	// Send the poll ID and the admin token back in the response
+	return resp201(`{"pollId":"` + poll.ID() + `","adminToken":"` + adminToken + `"}`)
+}
+
+func (h *handler) editPoll() events.APIGatewayProxyResponse {
+	poll, errResp := h.getPollAsAdmin()
+	if errResp != nil {
+		return *errResp
+	}
+	// Ballots refer to choices by their indices, so editing the choices would silently change
+	// what existing ballots mean
+	const castMsg = "polls cannot be edited once a ballot has been cast"
+	if poll.BallotVersion() > 0 {
+		return resp409(castMsg)
+	}
+	// Unmarshal the request
+	var edit struct {
+		Prompt  string   `json:"prompt"`
+		Choices []string `json:"choices"`
+	}
+	if err := json.Unmarshal([]byte(h.req.Body), &edit); err != nil {
+		return resp400("invalid JSON")
+	}
+	// Validate the edited poll, since the choices must still suit its settings
+	poll.Edit(edit.Prompt, edit.Choices)
+	if err := poll.Validate(); err != nil {
+		return resp400(err.Error())
+	}
+	// The update only succeeds if the poll still has no ballots
+	return h.updatePoll(poll, h.store.EditPoll, resp409(castMsg))
+}
+
+func (h *handler) closePoll() events.APIGatewayProxyResponse {
+	poll, errResp := h.getPollAsAdmin()
+	if errResp != nil {
+		return *errResp
+	}
+	// Move the deadline forward to now
+	now := time.Now()
+	if poll.Status(now) != models.StatusOpen {
+		return resp409("only open polls can be closed")
+	}
+	poll.Close(now)
+	return h.updatePoll(poll, h.store.SchedulePoll, resp404("no poll found for the specified ID"))
+}
+
+func (h *handler) reopenPoll() events.APIGatewayProxyResponse {
+	poll, errResp := h.getPollAsAdmin()
+	if errResp != nil {
+		return *errResp
+	}
+	// Remove the deadline that has passed
+	now := time.Now()
+	if poll.Status(now) != models.StatusClosed {
+		return resp409("only closed polls can be reopened")
+	}
+	poll.Reopen(now)
+	return h.updatePoll(poll, h.store.SchedulePoll, resp404("no poll found for the specified ID"))
+}
+
+func (h *handler) reschedulePoll() events.APIGatewayProxyResponse {
+	poll, errResp := h.getPollAsAdmin()
+	if errResp != nil {
+		return *errResp
+	}
+	// Unmarshal the request, where a missing time leaves that end of the voting window unbounded
+	var schedule struct {
+		OpensAt  *time.Time `json:"opensAt"`
+		ClosesAt *time.Time `json:"closesAt"`
+	}
+	if err := json.Unmarshal([]byte(h.req.Body), &schedule); err != nil {
+		return resp400("invalid JSON")
+	}
+	poll.Reschedule(schedule.OpensAt, schedule.ClosesAt, time.Now())
+	if err := poll.Validate(); err != nil {
+		return resp400(err.Error())
+	}
+	return h.updatePoll(poll, h.store.SchedulePoll, resp404("no poll found for the specified ID"))
+}
+
+func (h *handler) deletePoll() events.APIGatewayProxyResponse {
+	poll, errResp := h.getPollAsAdmin()
+	if errResp != nil {
+		return *errResp
+	}
+	if err := h.store.DeletePoll(poll.ID()); err != nil {
+		return resp500("failed to delete the poll from the database")
+	}
+	return resp200(`{"message":"successfully deleted poll"}`)
+}
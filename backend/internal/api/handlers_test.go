@@ -4,11 +4,16 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/noahkawaguchi/verdict/backend/internal/api"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
 )
 
 func quickJSON(anyStruct any) string {
@@ -39,6 +44,19 @@ func TestCreatePollHandler_Error(t *testing.T) {
 				Choices: []string{"Wednesday", "Tuesday", "None of the above", "Tuesday"},
 			}),
 		},
+		{
+			http.StatusBadRequest,
+			"unsupported tabulation method",
+			quickJSON(struct {
+				Prompt  string   `json:"prompt"`
+				Choices []string `json:"choices"`
+				Method  string   `json:"method"`
+			}{
+				Prompt:  "What is the best day of the week?",
+				Choices: []string{"Wednesday", "Tuesday", "None of the above"},
+				Method:  "coinFlip",
+			}),
+		},
 		{
 			http.StatusInternalServerError,
 			"failed to put the poll in the database",
@@ -87,6 +105,17 @@ func TestCreatePollHandler_Success(t *testing.T) {
 			Prompt:  "What is the worst day of the week?",
 			Choices: []string{"Monday", "Thursday", "Either Monday or Thursday"},
 		}),
+		quickJSON(struct {
+			Prompt  string    `json:"prompt"`
+			Choices []string  `json:"choices"`
+			Method  string    `json:"method"`
+			Points  []float64 `json:"points"`
+		}{
+			Prompt:  "Which feature should we build next?",
+			Choices: []string{"Dark mode", "Export", "Notifications"},
+			Method:  "positional",
+			Points:  []float64{5, 2, 0},
+		}),
 	}
 
 	for _, test := range tests {
@@ -101,7 +130,8 @@ func TestCreatePollHandler_Success(t *testing.T) {
 			t.Error("unexpected status code:", resp.StatusCode)
 		}
 		var respStruct struct {
-			PollID string `json:"pollId"`
+			PollID     string `json:"pollId"`
+			AdminToken string `json:"adminToken"`
 		}
 		if err := json.Unmarshal([]byte(resp.Body), &respStruct); err != nil {
 			t.Error("unexpected error unmarshaling JSON:", err)
@@ -109,6 +139,9 @@ func TestCreatePollHandler_Success(t *testing.T) {
 		if respStruct.PollID == "" {
 			t.Error("unexpectedly empty poll ID in response body:", resp.Body)
 		}
+		if respStruct.AdminToken == "" {
+			t.Error("unexpectedly empty admin token in response body:", resp.Body)
+		}
 	}
 }
 
@@ -202,33 +235,98 @@ func TestGetPollInfoHandler_Success(t *testing.T) {
 }
 
 func TestCastBallotHandler_Error(t *testing.T) {
+	fourChoices := func(pollID string) (*models.Poll, error) {
+		return models.NewPoll("What is the best season?",
+			[]string{"spring", "summer", "fall", "winter"}), nil
+	}
+	partial := func(pollID string) (*models.Poll, error) {
+		return models.NewPoll("What is the best season?",
+			[]string{"spring", "summer", "fall", "winter"}, models.WithPartialRankings(2)), nil
+	}
+	scheduled := func(opensAt, closesAt time.Time) func(pollID string) (*models.Poll, error) {
+		return func(pollID string) (*models.Poll, error) {
+			return models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"},
+				models.WithSchedule(&opensAt, &closesAt)), nil
+		}
+	}
+	ballotJSON := func(pollID string, rankOrder []int) string {
+		return quickJSON(struct {
+			PollID    string `json:"pollId"`
+			RankOrder []int  `json:"rankOrder"`
+		}{pollID, rankOrder})
+	}
 	tests := []struct {
-		statusCode int
-		errMsg     string
-		body       string
+		statusCode  int
+		errMsg      string
+		body        string
+		getPollMock func(pollID string) (*models.Poll, error)
 	}{
-		{http.StatusBadRequest, "invalid JSON", `{"pollId":"poll1,"rankOrder":[1, 0, 3, 2]}`},
+		{
+			http.StatusBadRequest,
+			"invalid JSON",
+			`{"pollId":"poll1,"rankOrder":[1, 0, 3, 2]}`,
+			fourChoices,
+		},
+		{
+			http.StatusBadRequest,
+			"poll ID cannot be empty",
+			ballotJSON("", []int{1, 0, 3, 2}),
+			fourChoices,
+		},
+		{
+			http.StatusInternalServerError,
+			"failed to get the poll from the database",
+			ballotJSON("poll21", []int{1, 0, 3, 2}),
+			func(pollID string) (*models.Poll, error) { return nil, errors.New("mock error") },
+		},
+		{
+			http.StatusNotFound,
+			"no poll found for the specified ID",
+			ballotJSON("poll21", []int{1, 0, 3, 2}),
+			func(pollID string) (*models.Poll, error) { return models.NewPoll("", nil), nil },
+		},
+		{
+			http.StatusForbidden,
+			"the poll is not open for voting yet",
+			ballotJSON("poll21", []int{1, 0, 3, 2}),
+			scheduled(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)),
+		},
+		{
+			http.StatusConflict,
+			"the poll is closed to new ballots",
+			ballotJSON("poll21", []int{1, 0, 3, 2}),
+			scheduled(time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour)),
+		},
 		{
 			http.StatusBadRequest,
 			"not a valid rank order",
-			quickJSON(struct {
-				PollID    string `json:"pollId"`
-				RankOrder []int  `json:"rankOrder"`
-			}{
-				PollID:    "poll22",
-				RankOrder: []int{2, 4, 3, 1},
-			}),
+			ballotJSON("poll22", []int{2, 4, 3, 1}),
+			fourChoices,
+		},
+		{
+			http.StatusBadRequest,
+			"there must be at least 4 rankings",
+			ballotJSON("poll22", []int{2, 0, 3}),
+			fourChoices,
+		},
+		{
+			http.StatusBadRequest,
+			"there must be at least 2 rankings",
+			ballotJSON("poll22", []int{2}),
+			partial,
+		},
+		{
+			http.StatusBadRequest,
+			"not a valid rank order",
+			ballotJSON("poll22", []int{2, 2}),
+			partial,
 		},
 		{
 			http.StatusInternalServerError,
 			"failed to put the ballot in the database",
-			quickJSON(struct {
-				PollID    string `json:"pollId"`
-				RankOrder []int  `json:"rankOrder"`
-			}{
-				PollID:    "poll23",
-				RankOrder: []int{2, 0, 3, 1},
-			}),
+			ballotJSON("poll23", []int{2, 0, 3, 1}),
+			fourChoices,
 		},
 	}
 
@@ -239,6 +337,7 @@ func TestCastBallotHandler_Error(t *testing.T) {
 			Body:       test.body,
 		}
 		handler := api.NewHandler(&mockDatastore{
+			GetPollMock:   test.getPollMock,
 			PutBallotMock: func(ballot *models.Ballot) error { return errors.New("mock error") },
 		}, req)
 		resp := handler.Route()
@@ -252,32 +351,72 @@ func TestCastBallotHandler_Error(t *testing.T) {
 }
 
 func TestCastBallotHandler_Success(t *testing.T) {
-	tests := []string{
-		quickJSON(struct {
-			PollID    string `json:"pollId"`
-			RankOrder []int  `json:"rankOrder"`
-		}{
-			PollID:    "poll23",
-			RankOrder: []int{2, 0, 3, 1},
-		}),
-		quickJSON(struct {
-			PollID    string `json:"pollId"`
-			UserID    string `json:"userId"`
-			RankOrder []int  `json:"rankOrder"`
-		}{
-			PollID:    "poll24",
-			UserID:    "user123",
-			RankOrder: []int{0, 3, 1, 4, 2, 5},
-		}),
+	tests := []struct {
+		body string
+		poll *models.Poll
+	}{
+		{
+			quickJSON(struct {
+				PollID    string `json:"pollId"`
+				RankOrder []int  `json:"rankOrder"`
+			}{
+				PollID:    "poll23",
+				RankOrder: []int{2, 0, 3, 1},
+			}),
+			models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"}),
+		},
+		{
+			quickJSON(struct {
+				PollID    string `json:"pollId"`
+				UserID    string `json:"userId"`
+				RankOrder []int  `json:"rankOrder"`
+			}{
+				PollID:    "poll24",
+				UserID:    "user123",
+				RankOrder: []int{0, 3, 1, 4, 2, 5},
+			}),
+			models.NewPoll("What is the best number?", []string{"0", "1", "2", "3", "4", "5"}),
+		},
+		{
+			quickJSON(struct {
+				PollID    string `json:"pollId"`
+				RankOrder []int  `json:"rankOrder"`
+			}{
+				PollID:    "poll25",
+				RankOrder: []int{3},
+			}),
+			models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"}, models.WithPartialRankings(0)),
+		},
+		{
+			`{"pollId":"poll26","rankOrder":[[1,2],0,3]}`,
+			models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"}),
+		},
+		{
+			`{"pollId":"poll27","rankOrder":[4,1,2,0,3],"writeIns":["monsoon"]}`,
+			models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"}, models.WithWriteIns()),
+		},
+		{
+			`{"pollId":"poll28","rankOrder":[1,2,0,3]}`,
+			models.NewPoll("What is the best season?",
+				[]string{"spring", "summer", "fall", "winter"}, models.WithSchedule(
+					utils.Ref(time.Now().Add(-time.Hour)), utils.Ref(time.Now().Add(time.Hour)),
+				)),
+		},
 	}
 
 	for _, test := range tests {
 		req := events.APIGatewayProxyRequest{
 			HTTPMethod: http.MethodPost,
 			Path:       "/ballot",
-			Body:       test,
+			Body:       test.body,
 		}
-		handler := api.NewHandler(&mockDatastore{}, req)
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock: func(pollID string) (*models.Poll, error) { return test.poll, nil },
+		}, req)
 		resp := handler.Route()
 		if resp.StatusCode != http.StatusCreated {
 			t.Error("unexpected status code:", resp.StatusCode)
@@ -288,6 +427,43 @@ func TestCastBallotHandler_Success(t *testing.T) {
 	}
 }
 
+func TestCastBallotHandler_VoterRoll(t *testing.T) {
+	poll := models.NewPoll("What is the best season?",
+		[]string{"spring", "summer", "fall", "winter"},
+		models.WithVoterRoll(map[string]float64{"user1": 3}))
+	tests := []struct {
+		body       string
+		statusCode int
+	}{
+		{`{"pollId":"poll28","userId":"user1","rankOrder":[2,0,3,1]}`, http.StatusCreated},
+		{`{"pollId":"poll28","userId":"user2","rankOrder":[2,0,3,1]}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       "/ballot",
+			Body:       test.body,
+		}
+		var putWeight float64
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock: func(pollID string) (*models.Poll, error) { return poll, nil },
+			PutBallotMock: func(ballot *models.Ballot) error {
+				putWeight = ballot.Weight()
+				return nil
+			},
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				test.statusCode, resp.StatusCode)
+		}
+		// The ballot is stored with the weight from the voter roll
+		if test.statusCode == http.StatusCreated && putWeight != 3 {
+			t.Error("unexpected ballot weight:", putWeight)
+		}
+	}
+}
+
 func TestGetResultHandler_Error(t *testing.T) {
 	tests := []struct {
 		statusCode     int
@@ -439,3 +615,675 @@ func TestGetResultHandler_Success(t *testing.T) {
 		}
 	}
 }
+
+func TestGetResultHandler_TallySnapshot(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 2, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0, 2}),
+		models.NewBallot(poll.ID(), "user3", []int{0, 1, 2}),
+	}
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/result/" + poll.ID(),
+		PathParameters: map[string]string{"pollId": poll.ID()},
+	}
+	expected, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectedBody, err := json.Marshal(expected)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	tests := []struct {
+		name    string
+		tally   *models.Tally
+		rebuild bool
+	}{
+		{"current snapshot", models.TallyOf(poll, ballots), false},
+		{"missing snapshot", nil, true},
+		{"outdated snapshot", models.NewTally(poll.ID(), 3, nil), true},
+	}
+	for _, test := range tests {
+		var ballotsRead, tallyPut bool
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock:  func(pollID string) (*models.Poll, error) { return poll, nil },
+			GetTallyMock: func(pollID string) (*models.Tally, error) { return test.tally, nil },
+			GetBallotsMock: func(pollID string) ([]*models.Ballot, error) {
+				ballotsRead = true
+				return ballots, nil
+			},
+			PutTallyMock: func(tally *models.Tally) error {
+				tallyPut = true
+				return nil
+			},
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != http.StatusOK || resp.Body != string(expectedBody) {
+			t.Errorf("%s: unexpected response: %d %s", test.name, resp.StatusCode, resp.Body)
+		}
+		if ballotsRead != test.rebuild || tallyPut != test.rebuild {
+			t.Errorf("%s: expected rebuild to be %v, got ballots read %v and tally put %v",
+				test.name, test.rebuild, ballotsRead, tallyPut)
+		}
+	}
+}
+
+func TestGetPairwiseHandler_Error(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	tests := []struct {
+		statusCode     int
+		errMsg         string
+		getBallotsMock func(pollID string) ([]*models.Ballot, error)
+	}{
+		{
+			http.StatusInternalServerError,
+			"failed to get the poll's ballots from the database",
+			func(pollID string) ([]*models.Ballot, error) {
+				return nil, errors.New("mock error")
+			},
+		},
+		{
+			http.StatusNotFound,
+			"no ballots found for the specified poll",
+			func(pollID string) ([]*models.Ballot, error) {
+				return []*models.Ballot{}, nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:     http.MethodGet,
+			Path:           "/pairwise/" + poll.ID(),
+			PathParameters: map[string]string{"pollId": poll.ID()},
+		}
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+			GetBallotsMock: test.getBallotsMock,
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code: expected %d, got %d", test.statusCode, resp.StatusCode)
+		}
+		if resp.Body != `{"error":"`+test.errMsg+`"}` {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestGetPairwiseHandler_Success(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 2, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user3", []int{1, 0, 2}),
+	}
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/pairwise/" + poll.ID(),
+		PathParameters: map[string]string{"pollId": poll.ID()},
+	}
+	handler := api.NewHandler(&mockDatastore{
+		GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+		GetBallotsMock: func(pollID string) ([]*models.Ballot, error) { return ballots, nil },
+	}, req)
+	resp := handler.Route()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	expected := `{"prompt":"What is the best day of the week?","totalVotes":3,` +
+		`"choices":["Wednesday","Tuesday","None of the above"],` +
+		`"preferences":[[0,2,3],[1,0,2],[0,1,0]],` +
+		`"condorcetWinner":"Wednesday","condorcetLoser":"None of the above",` +
+		`"smithSet":["Wednesday"]}`
+	if resp.Body != expected {
+		t.Error("unexpected response body:", resp.Body)
+		t.Error("expected:", expected)
+	}
+}
+
+func TestGetWithdrawalHandler_Error(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{models.NewBallot(poll.ID(), "user1", []int{0, 2, 1})}
+	tests := []struct {
+		withdraw []string
+		errMsg   string
+	}{
+		{nil, "at least one choice must be withdrawn"},
+		{[]string{"Thursday"}, "only the poll's choices can be withdrawn"},
+		{[]string{"Tuesday", "Tuesday"}, "each choice can only be withdrawn once"},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:                      http.MethodGet,
+			Path:                            "/withdrawal/" + poll.ID(),
+			PathParameters:                  map[string]string{"pollId": poll.ID()},
+			MultiValueQueryStringParameters: map[string][]string{"withdraw": test.withdraw},
+		}
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+			GetBallotsMock: func(pollID string) ([]*models.Ballot, error) { return ballots, nil },
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				http.StatusBadRequest, resp.StatusCode)
+		}
+		if resp.Body != `{"error":"`+test.errMsg+`"}` {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestGetWithdrawalHandler_Success(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user2", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user3", []int{1, 0, 2}),
+		models.NewBallot(poll.ID(), "user4", []int{2, 1, 0}),
+		models.NewBallot(poll.ID(), "user5", []int{2, 1, 0}),
+	}
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/withdrawal/" + poll.ID(),
+		PathParameters: map[string]string{"pollId": poll.ID()},
+		MultiValueQueryStringParameters: map[string][]string{
+			"withdraw": {"None of the above"},
+		},
+	}
+	handler := api.NewHandler(&mockDatastore{
+		GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+		GetBallotsMock: func(pollID string) ([]*models.Ballot, error) { return ballots, nil },
+	}, req)
+	resp := handler.Route()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	expected := `{"prompt":"What is the best day of the week?","totalVotes":5,` +
+		`"withdrawn":["None of the above"],"originalWinner":"Wednesday",` +
+		`"winner":"Tuesday","winnerChanged":true,"spoiler":true,"winningRound":1,` +
+		`"rounds":[{"round":1,"votes":{"Tuesday":3,"Wednesday":2},"eliminations":[]}]}`
+	if resp.Body != expected {
+		t.Error("unexpected response body:", resp.Body)
+		t.Error("expected:", expected)
+	}
+}
+
+func TestExportPollHandler(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 2, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0, 2}),
+		models.NewBallot(poll.ID(), "user3", []int{0, 2, 1}),
+	}
+	tests := []struct {
+		format, contentType, body string
+		statusCode                int
+	}{
+		{
+			"blt",
+			"text/plain; charset=utf-8",
+			"3 1\n2 1 3 2 0\n1 2 1 3 0\n0\n" +
+				"\"Wednesday\"\n\"Tuesday\"\n\"None of the above\"\n" +
+				"\"What is the best day of the week?\"\n",
+			http.StatusOK,
+		},
+		{"", "application/json", `{"error":"unsupported export format"}`, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/export/" + poll.ID(),
+			PathParameters:        map[string]string{"pollId": poll.ID()},
+			QueryStringParameters: map[string]string{"format": test.format},
+		}
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+			GetBallotsMock: func(pollID string) ([]*models.Ballot, error) { return ballots, nil },
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				test.statusCode, resp.StatusCode)
+		}
+		if resp.Headers["Content-Type"] != test.contentType {
+			t.Error("unexpected content type:", resp.Headers["Content-Type"])
+		}
+		if resp.Body != test.body {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestExportPollHandler_Error(t *testing.T) {
+	tests := []struct {
+		format, errMsg string
+		poll           *models.Poll
+	}{
+		{
+			"blt", "names with double quotes or line breaks cannot be exported to BLT",
+			models.NewPoll(`Which is the "best" day?`, []string{"Wednesday", "Tuesday"}),
+		},
+		{
+			"cvr", "ballots with fractional weights cannot be exported to CVR",
+			models.NewPoll("Which is the best day?", []string{"Wednesday", "Tuesday"},
+				models.WithVoterRoll(map[string]float64{"user1": 0.5})),
+		},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodGet,
+			Path:                  "/export/" + test.poll.ID(),
+			PathParameters:        map[string]string{"pollId": test.poll.ID()},
+			QueryStringParameters: map[string]string{"format": test.format},
+		}
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock: func(pollID string) (*models.Poll, error) { return test.poll, nil },
+			GetBallotsMock: func(pollID string) ([]*models.Ballot, error) {
+				return []*models.Ballot{models.NewBallot(pollID, "user1", []int{0, 1})}, nil
+			},
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				http.StatusBadRequest, resp.StatusCode)
+		}
+		if expected := `{"error":"` + test.errMsg + `"}`; resp.Body != expected {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestImportPollHandler_Error(t *testing.T) {
+	blt := "2 1\n3 1 2 0\n0\n\"yes\"\n\"no\"\n\"Should we adopt the bylaws?\"\n"
+	tests := []struct {
+		format, body, errMsg string
+		statusCode           int
+		putPollMock          func(poll *models.Poll) error
+		putBallotsMock       func(ballots []*models.Ballot) error
+	}{
+		{"csv", blt, "unsupported import format", http.StatusBadRequest, nil, nil},
+		{"cvr", blt, "invalid JSON", http.StatusBadRequest, nil, nil},
+		{
+			"blt", "2 1\n", "the ballots must be followed by a line with only 0",
+			http.StatusBadRequest, nil, nil,
+		},
+		{
+			"blt", blt, "failed to put the poll in the database", http.StatusInternalServerError,
+			func(poll *models.Poll) error { return errors.New("mock error") }, nil,
+		},
+		{
+			"blt", blt, "failed to put the ballots in the database",
+			http.StatusInternalServerError,
+			nil, func(ballots []*models.Ballot) error { return errors.New("mock error") },
+		},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:            http.MethodPost,
+			Path:                  "/import",
+			QueryStringParameters: map[string]string{"format": test.format},
+			Body:                  test.body,
+		}
+		handler := api.NewHandler(&mockDatastore{
+			PutPollMock:    test.putPollMock,
+			PutBallotsMock: test.putBallotsMock,
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				test.statusCode, resp.StatusCode)
+		}
+		if resp.Body != `{"error":"`+test.errMsg+`"}` {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestImportPollHandler_Success(t *testing.T) {
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodPost,
+		Path:                  "/import",
+		QueryStringParameters: map[string]string{"format": "blt"},
+		Body: "2 1\n3 1 2 0\n2 2 1 0\n0\n" +
+			"\"yes\"\n\"no\"\n\"Should we adopt the bylaws?\"\n",
+	}
+	var putPoll *models.Poll
+	var putBallots []*models.Ballot
+	handler := api.NewHandler(&mockDatastore{
+		PutPollMock: func(poll *models.Poll) error {
+			putPoll = poll
+			return nil
+		},
+		PutBallotsMock: func(ballots []*models.Ballot) error {
+			putBallots = ballots
+			return nil
+		},
+	}, req)
+	resp := handler.Route()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("unexpected status code: expected %d, got %d",
+			http.StatusCreated, resp.StatusCode)
+	}
+	var respStruct struct {
+		PollID     string `json:"pollId"`
+		AdminToken string `json:"adminToken"`
+	}
+	if err := json.Unmarshal([]byte(resp.Body), &respStruct); err != nil {
+		t.Error("unexpected error unmarshaling JSON:", err)
+	}
+	if respStruct.PollID != putPoll.ID() || !putPoll.IsAdmin(respStruct.AdminToken) {
+		t.Error("unexpected response body:", resp.Body)
+	}
+	if len(putBallots) != 5 || putBallots[0].PollID() != putPoll.ID() {
+		t.Error("unexpected ballots:", putBallots)
+	}
+}
+
+func TestExportPollHandler_CVR(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 2, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0, 2}),
+	}
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:            http.MethodGet,
+		Path:                  "/export/" + poll.ID(),
+		PathParameters:        map[string]string{"pollId": poll.ID()},
+		QueryStringParameters: map[string]string{"format": "cvr"},
+	}
+	handler := api.NewHandler(&mockDatastore{
+		GetPollMock:    func(pollID string) (*models.Poll, error) { return poll, nil },
+		GetBallotsMock: func(pollID string) ([]*models.Ballot, error) { return ballots, nil },
+	}, req)
+	resp := handler.Route()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("unexpected status code: expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if resp.Headers["Content-Type"] != "application/json" {
+		t.Error("unexpected content type:", resp.Headers["Content-Type"])
+	}
+	// The exported report can be imported as a new poll with the same ballots
+	imported, importedBallots, err := models.ParseCVR([]byte(resp.Body))
+	if err != nil {
+		t.Fatal("expected a valid report, got:", err)
+	}
+	importedBLT, err := models.FormatBLT(imported, importedBallots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expectedBLT, err := models.FormatBLT(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if importedBLT != expectedBLT {
+		t.Error("unexpected response body:", resp.Body)
+	}
+}
+
+func TestManagePollHandlers_Error(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	adminToken := poll.NewAdminToken()
+	// Handlers update the poll they get, so undo the changes from any previous request
+	getPoll := func(pollID string) (*models.Poll, error) {
+		poll.Reschedule(nil, nil, time.Now())
+		return poll, nil
+	}
+	closed := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"},
+		models.WithSchedule(nil, utils.Ref(time.Now().Add(-time.Hour))))
+	closedToken := closed.NewAdminToken()
+	tests := []struct {
+		method, path, pollID, token, body, errMsg string
+		statusCode                                int
+		store                                     *mockDatastore
+	}{
+		{
+			http.MethodPost, "/close/poll1", "", adminToken, "", "missing poll ID",
+			http.StatusBadRequest, &mockDatastore{},
+		},
+		{
+			http.MethodPost, "/close/poll1", "poll1", "", "", "missing admin token",
+			http.StatusUnauthorized, &mockDatastore{},
+		},
+		{
+			http.MethodPost, "/reopen/poll1", "poll1", adminToken, "",
+			"failed to get the poll from the database", http.StatusInternalServerError,
+			&mockDatastore{GetPollMock: func(pollID string) (*models.Poll, error) {
+				return nil, errors.New("mock error")
+			}},
+		},
+		{
+			http.MethodPut, "/schedule/poll1", "poll1", adminToken, "{}",
+			"no poll found for the specified ID", http.StatusNotFound,
+			&mockDatastore{GetPollMock: func(pollID string) (*models.Poll, error) {
+				return models.NewPoll("", nil), nil
+			}},
+		},
+		{
+			http.MethodDelete, "/poll/poll1", "poll1", closedToken, "", "invalid admin token",
+			http.StatusForbidden,
+			&mockDatastore{GetPollMock: getPoll},
+		},
+		{
+			http.MethodPost, "/close/poll1", "poll1", closedToken, "",
+			"only open polls can be closed", http.StatusConflict,
+			&mockDatastore{GetPollMock: func(pollID string) (*models.Poll, error) {
+				return closed, nil
+			}},
+		},
+		{
+			http.MethodPost, "/reopen/poll1", "poll1", adminToken, "",
+			"only closed polls can be reopened", http.StatusConflict,
+			&mockDatastore{GetPollMock: getPoll},
+		},
+		{
+			http.MethodPut, "/schedule/poll1", "poll1", adminToken, `{"closesAt":"tomorrow"}`,
+			"invalid JSON", http.StatusBadRequest,
+			&mockDatastore{GetPollMock: getPoll},
+		},
+		{
+			http.MethodPut, "/schedule/poll1", "poll1", adminToken,
+			`{"opensAt":"2025-03-08T17:00:00Z","closesAt":"2025-03-01T09:00:00Z"}`,
+			"the closing time must be after the opening time", http.StatusBadRequest,
+			&mockDatastore{GetPollMock: getPoll},
+		},
+		{
+			http.MethodPost, "/close/poll1", "poll1", adminToken, "",
+			"no poll found for the specified ID", http.StatusNotFound,
+			&mockDatastore{
+				GetPollMock:      getPoll,
+				SchedulePollMock: func(poll *models.Poll) (bool, error) { return false, nil },
+			},
+		},
+		{
+			http.MethodPost, "/close/poll1", "poll1", adminToken, "",
+			"failed to update the poll in the database", http.StatusInternalServerError,
+			&mockDatastore{
+				GetPollMock: getPoll,
+				SchedulePollMock: func(poll *models.Poll) (bool, error) {
+					return false, errors.New("mock error")
+				},
+			},
+		},
+		{
+			http.MethodDelete, "/poll/poll1", "poll1", adminToken, "",
+			"failed to delete the poll from the database", http.StatusInternalServerError,
+			&mockDatastore{
+				GetPollMock:    getPoll,
+				DeletePollMock: func(pollID string) error { return errors.New("mock error") },
+			},
+		},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:     test.method,
+			Path:           test.path,
+			PathParameters: map[string]string{"pollId": test.pollID},
+			Headers:        map[string]string{"Authorization": "Bearer " + test.token},
+			Body:           test.body,
+		}
+		resp := api.NewHandler(test.store, req).Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code for %s %s: expected %d, got %d",
+				test.method, test.path, test.statusCode, resp.StatusCode)
+		}
+		if resp.Body != `{"error":"`+test.errMsg+`"}` {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+}
+
+func TestManagePollHandlers_Success(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the week?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	adminToken := poll.NewAdminToken()
+	tests := []struct {
+		method, path, body string
+		expected           models.PollStatus
+	}{
+		{http.MethodPost, "/close/poll1", "", models.StatusClosed},
+		{http.MethodPost, "/reopen/poll1", "", models.StatusOpen},
+		{
+			http.MethodPut, "/schedule/poll1", `{"opensAt":"2999-01-01T00:00:00Z"}`,
+			models.StatusDraft,
+		},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:     test.method,
+			Path:           test.path,
+			PathParameters: map[string]string{"pollId": "poll1"},
+			// Header names are case-insensitive
+			Headers: map[string]string{"authorization": "Bearer " + adminToken},
+			Body:    test.body,
+		}
+		var updated *models.Poll
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock: func(pollID string) (*models.Poll, error) { return poll, nil },
+			SchedulePollMock: func(poll *models.Poll) (bool, error) {
+				updated = poll
+				return true, nil
+			},
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status code: expected %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+		if updated == nil || updated.Status(time.Now()) != test.expected {
+			t.Errorf("expected the poll to be updated to %q", test.expected)
+		}
+		if resp.Body != quickJSON(updated) {
+			t.Error("unexpected response body:", resp.Body)
+		}
+	}
+	// Deleting the poll
+	req := events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodDelete,
+		Path:           "/poll/poll1",
+		PathParameters: map[string]string{"pollId": "poll1"},
+		Headers:        map[string]string{"Authorization": "Bearer " + adminToken},
+	}
+	var deleted string
+	handler := api.NewHandler(&mockDatastore{
+		GetPollMock: func(pollID string) (*models.Poll, error) { return poll, nil },
+		DeletePollMock: func(pollID string) error {
+			deleted = pollID
+			return nil
+		},
+	}, req)
+	resp := handler.Route()
+	if resp.StatusCode != http.StatusOK || resp.Body != `{"message":"successfully deleted poll"}` {
+		t.Error("unexpected response:", resp.StatusCode, resp.Body)
+	}
+	if deleted != poll.ID() {
+		t.Error("unexpected deleted poll ID:", deleted)
+	}
+}
+
+func TestEditPollHandler(t *testing.T) {
+	poll := models.NewPoll("What is the best day of the weak?",
+		[]string{"Wednesday", "Tuesday", "None of the above"})
+	adminToken := poll.NewAdminToken()
+	// A poll is retrieved from the database with the number of ballots cast in it
+	withBallots := func(ballotVersion int) func(pollID string) (*models.Poll, error) {
+		return func(pollID string) (*models.Poll, error) {
+			av, err := attributevalue.MarshalMap(poll)
+			if err != nil {
+				return nil, err
+			}
+			av["BallotVersion"] = &types.AttributeValueMemberN{Value: strconv.Itoa(ballotVersion)}
+			var retrieved *models.Poll
+			return retrieved, attributevalue.UnmarshalMap(av, &retrieved)
+		}
+	}
+	edit := `{"prompt":"What is the best day of the week?",` +
+		`"choices":["Wednesday","Tuesday","Saturday"]}`
+	tests := []struct {
+		body, expected string
+		statusCode     int
+		ballotVersion  int
+		updated        bool
+	}{
+		{
+			edit, `{"error":"polls cannot be edited once a ballot has been cast"}`,
+			http.StatusConflict, 1, true,
+		},
+		{`{"prompt":3}`, `{"error":"invalid JSON"}`, http.StatusBadRequest, 0, true},
+		{
+			`{"prompt":"What is the best day of the week?","choices":["Tuesday","Tuesday"]}`,
+			`{"error":"choices must be unique"}`, http.StatusBadRequest, 0, true,
+		},
+		// A ballot is cast after the poll is retrieved
+		{
+			edit, `{"error":"polls cannot be edited once a ballot has been cast"}`,
+			http.StatusConflict, 0, false,
+		},
+		{
+			edit, `{"prompt":"What is the best day of the week?",` +
+				`"choices":["Wednesday","Tuesday","Saturday"],"status":"open"}`,
+			http.StatusOK, 0, true,
+		},
+	}
+	for _, test := range tests {
+		req := events.APIGatewayProxyRequest{
+			HTTPMethod:     http.MethodPut,
+			Path:           "/poll/" + poll.ID(),
+			PathParameters: map[string]string{"pollId": poll.ID()},
+			Headers:        map[string]string{"Authorization": "Bearer " + adminToken},
+			Body:           test.body,
+		}
+		var stored *models.Poll
+		handler := api.NewHandler(&mockDatastore{
+			GetPollMock: withBallots(test.ballotVersion),
+			EditPollMock: func(poll *models.Poll) (bool, error) {
+				stored = poll
+				return test.updated, nil
+			},
+		}, req)
+		resp := handler.Route()
+		if resp.StatusCode != test.statusCode {
+			t.Errorf("unexpected status code: expected %d, got %d",
+				test.statusCode, resp.StatusCode)
+		}
+		if resp.Body != test.expected {
+			t.Error("unexpected response body:", resp.Body)
+		}
+		// The edited poll keeps its ID and admin token
+		if test.statusCode == http.StatusOK &&
+			(stored.ID() != poll.ID() || !stored.IsAdmin(adminToken)) {
+			t.Error("unexpected stored poll:", stored)
+		}
+	}
+}
@@ -10,8 +10,14 @@ import (
 type datastore interface {
 	PutPoll(poll *models.Poll) error
 	GetPoll(pollID string) (*models.Poll, error)
+	EditPoll(poll *models.Poll) (bool, error)
+	SchedulePoll(poll *models.Poll) (bool, error)
+	DeletePoll(pollID string) error
 	PutBallot(ballot *models.Ballot) error
+	PutBallots(ballots []*models.Ballot) error
 	GetBallots(pollID string) ([]*models.Ballot, error)
+	GetTally(pollID string) (*models.Tally, error)
+	PutTally(tally *models.Tally) error
 }
 
 type handler struct {
@@ -32,6 +38,14 @@ func (h *handler) Route() events.APIGatewayProxyResponse {
 			return h.createPoll()
 		case "/ballot":
 			return h.castBallot()
+		case "/import":
+			return h.importPoll()
+		}
+		switch getShortPath(h.req.Path) {
+		case "/close":
+			return h.closePoll()
+		case "/reopen":
+			return h.reopenPoll()
 		default:
 			return resp404("path not found for method POST: " + h.req.Path)
 		}
@@ -41,10 +55,32 @@ func (h *handler) Route() events.APIGatewayProxyResponse {
 			return h.getPollInfo()
 		case "/result":
 			return h.getResult()
+		case "/pairwise":
+			return h.getPairwise()
+		case "/withdrawal":
+			return h.getWithdrawal()
+		case "/export":
+			return h.exportPoll()
 		default:
 			return resp404("path not found for method GET: " + h.req.Path)
 		}
+	case http.MethodPut:
+		switch getShortPath(h.req.Path) {
+		case "/poll":
+			return h.editPoll()
+		case "/schedule":
+			return h.reschedulePoll()
+		default:
+			return resp404("path not found for method PUT: " + h.req.Path)
+		}
+	case http.MethodDelete:
+		switch getShortPath(h.req.Path) {
+		case "/poll":
+			return h.deletePoll()
+		default:
+			return resp404("path not found for method DELETE: " + h.req.Path)
+		}
 	default:
-		return resp405(h.req.HTTPMethod, "OPTIONS", "GET", "POST")
+		return resp405(h.req.HTTPMethod, "OPTIONS", "GET", "POST", "PUT", "DELETE")
 	}
 }
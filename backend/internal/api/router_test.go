@@ -12,14 +12,6 @@ import (
 
 func TestRouter_MethodNotAllowed(t *testing.T) {
 	tests := []events.APIGatewayProxyRequest{
-		{
-			Path:       "/poll",
-			HTTPMethod: http.MethodPut,
-		},
-		{
-			Path:       "/ballot",
-			HTTPMethod: http.MethodPut,
-		},
 		{
 			Path:       "/poll",
 			HTTPMethod: http.MethodPatch,
@@ -29,12 +21,12 @@ func TestRouter_MethodNotAllowed(t *testing.T) {
 			HTTPMethod: http.MethodPatch,
 		},
 		{
-			Path:       "/poll",
-			HTTPMethod: http.MethodDelete,
+			Path:       "/schedule/poll1",
+			HTTPMethod: http.MethodPatch,
 		},
 		{
-			Path:       "/ballot",
-			HTTPMethod: http.MethodDelete,
+			Path:       "/poll/poll1",
+			HTTPMethod: http.MethodConnect,
 		},
 	}
 
@@ -47,9 +39,9 @@ func TestRouter_MethodNotAllowed(t *testing.T) {
 		expectedHeaders := map[string]string{
 			"Content-Type":                 "application/json",
 			"Access-Control-Allow-Origin":  os.Getenv("FRONTEND_URL"),
-			"Access-Control-Allow-Methods": "OPTIONS,GET,POST",
+			"Access-Control-Allow-Methods": "OPTIONS,GET,POST,PUT,DELETE",
 			"Access-Control-Allow-Headers": "Content-Type,Authorization",
-			"Allow":                        "OPTIONS, GET, POST",
+			"Allow":                        "OPTIONS, GET, POST, PUT, DELETE",
 		}
 		if !cmp.Equal(resp.Headers, expectedHeaders) {
 			t.Error("unexpected headers:", resp.Headers)
@@ -78,6 +70,26 @@ func TestRouter_PathNotFound(t *testing.T) {
 			Path:       "/poll-voting",
 			HTTPMethod: http.MethodGet,
 		},
+		{
+			Path:       "/close",
+			HTTPMethod: http.MethodPost,
+		},
+		{
+			Path:       "/poll",
+			HTTPMethod: http.MethodPut,
+		},
+		{
+			Path:       "/ballot",
+			HTTPMethod: http.MethodPut,
+		},
+		{
+			Path:       "/poll",
+			HTTPMethod: http.MethodDelete,
+		},
+		{
+			Path:       "/ballot/poll1",
+			HTTPMethod: http.MethodDelete,
+		},
 	}
 
 	for _, test := range tests {
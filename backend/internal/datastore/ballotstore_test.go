@@ -3,16 +3,19 @@ package datastore_test
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/noahkawaguchi/verdict/backend/internal/datastore"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
 )
 
 func TestPutBallot_Error(t *testing.T) {
 	tableStore := datastore.New(context.TODO(), &mockDynamo{
-		PutItemMock: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+		TransactWriteItemsMock: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
 			return nil, errors.New("mocked error")
 		},
 	})
@@ -30,10 +33,85 @@ func TestPutBallot_Error(t *testing.T) {
 	}
 }
 
+func TestPutBallots(t *testing.T) {
+	var batches [][]types.WriteRequest
+	var versionUpdate *dynamodb.UpdateItemInput
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		BatchWriteItemMock: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			batches = append(batches, params.RequestItems["Ballots"])
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+		UpdateItemMock: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			versionUpdate = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	})
+	var ballots []*models.Ballot
+	for i := range 30 {
+		ballots = append(ballots, models.NewBallot("poll1", "user"+strconv.Itoa(i), []int{0, 1}))
+	}
+	if err := tableStore.PutBallots(ballots); err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	// DynamoDB allows at most 25 writes per batch
+	if len(batches) != 2 || len(batches[0]) != 25 || len(batches[1]) != 5 {
+		t.Error("unexpected batches:", batches)
+	}
+	// The poll's ballot version counts the ballots afterward
+	if versionUpdate == nil || *versionUpdate.TableName != "Polls" ||
+		*versionUpdate.UpdateExpression != "ADD BallotVersion :count" {
+		t.Fatal("unexpected ballot version update:", versionUpdate)
+	}
+	count, _ := versionUpdate.ExpressionAttributeValues[":count"].(*types.AttributeValueMemberN)
+	if count == nil || count.Value != "30" {
+		t.Error("unexpected ballot count:", versionUpdate.ExpressionAttributeValues)
+	}
+}
+
+func TestPutBallots_Unprocessed(t *testing.T) {
+	tests := []struct {
+		unprocessedCalls, calls int
+		errMsg                  string
+	}{
+		{2, 3, ""},
+		{100, 6, "the database did not process every write in time"},
+	}
+	for _, test := range tests {
+		calls, versionUpdated := 0, false
+		tableStore := datastore.New(context.TODO(), &mockDynamo{
+			BatchWriteItemMock: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+				calls++
+				// The table is throttled, so the requests are handed back unprocessed
+				if calls <= test.unprocessedCalls {
+					return &dynamodb.BatchWriteItemOutput{
+						UnprocessedItems: params.RequestItems,
+					}, nil
+				}
+				return &dynamodb.BatchWriteItemOutput{}, nil
+			},
+			UpdateItemMock: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				versionUpdated = true
+				return &dynamodb.UpdateItemOutput{}, nil
+			},
+		})
+		ballot := models.NewBallot("poll1", "user1", []int{0, 1})
+		err := tableStore.PutBallots([]*models.Ballot{ballot})
+		if (err == nil) != (test.errMsg == "") || (err != nil && err.Error() != test.errMsg) {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+		// The ballot version only counts ballots that were all written
+		if calls != test.calls || versionUpdated != (test.errMsg == "") {
+			t.Errorf("unexpected calls or version update: %d, %t", calls, versionUpdated)
+		}
+	}
+}
+
 func TestPutBallot_Success(t *testing.T) {
+	var transactions [][]types.TransactWriteItem
 	tableStore := datastore.New(context.TODO(), &mockDynamo{
-		PutItemMock: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
-			return &dynamodb.PutItemOutput{}, nil
+		TransactWriteItemsMock: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			transactions = append(transactions, params.TransactItems)
+			return &dynamodb.TransactWriteItemsOutput{}, nil
 		},
 	})
 
@@ -48,6 +126,53 @@ func TestPutBallot_Success(t *testing.T) {
 			t.Error("expected success, got:", err)
 		}
 	}
+	// Each new ballot is put, the poll's ballot version is incremented, and the tally snapshot's
+	// count and version are incremented, all in one transaction
+	if len(transactions) != len(tests) {
+		t.Fatal("unexpected number of transactions:", len(transactions))
+	}
+	for i, items := range transactions {
+		if len(items) != 4 || items[0].Put == nil || items[0].Put.ConditionExpression == nil {
+			t.Fatalf("unexpected transaction items: %+v", items)
+		}
+		// The count is kept under the fixed-size hash of the ballot's rank key
+		count := items[2].Update
+		sortKey, ok := count.Key["RankKey"].(*types.AttributeValueMemberS)
+		ballot, _ := count.ExpressionAttributeValues[":ballot"].(*types.AttributeValueMemberS)
+		if !ok || len(sortKey.Value) != 64 || ballot == nil ||
+			ballot.Value != tests[i].RankKey() {
+			t.Errorf("unexpected count update: %+v", count)
+		}
+	}
+}
+
+func TestPutBallot_Replace(t *testing.T) {
+	var transactions [][]types.TransactWriteItem
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		TransactWriteItemsMock: func(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+			transactions = append(transactions, params.TransactItems)
+			if len(transactions) == 1 {
+				// The voter has already cast a ballot
+				return nil, &types.TransactionCanceledException{
+					CancellationReasons: []types.CancellationReason{
+						{Code: utils.Ref("ConditionalCheckFailed")},
+						{Code: utils.Ref("None")},
+					},
+				}
+			}
+			return &dynamodb.TransactWriteItemsOutput{}, nil
+		},
+	})
+	if err := tableStore.PutBallot(models.NewBallot("poll1", "user1", []int{0, 1})); err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	// The replacement skips the tally, leaving it out of date so that it is rebuilt
+	if len(transactions) != 2 || len(transactions[1]) != 2 {
+		t.Fatalf("unexpected transactions: %+v", transactions)
+	}
+	if put := transactions[1][0].Put; put == nil || put.ConditionExpression != nil {
+		t.Errorf("expected an unconditional put, got %+v", put)
+	}
 }
 
 func TestGetBallots_Error(t *testing.T) {
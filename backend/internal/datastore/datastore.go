@@ -2,8 +2,11 @@ package datastore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
@@ -13,7 +16,11 @@ import (
 type dynamoClient interface {
 	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
 	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
 	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
 }
 
 type dynamoStore struct {
@@ -27,13 +34,191 @@ var ballotsTableInfo = &tableInfo{"Ballots", "PollID", "UserID"}
 
 var pollsTableInfo = &tableInfo{name: "Polls", partitionKey: "PollID"} // No sort key
 
+var talliesTableInfo = &tableInfo{"Tallies", "PollID", "RankKey"}
+
 func New(ctx context.Context, client dynamoClient) *dynamoStore { return &dynamoStore{ctx, client} }
 
 // PutPoll creates a new poll entry in the database.
 func (ds *dynamoStore) PutPoll(poll *models.Poll) error { return storeItem(ds, poll) }
 
-// PutBallot creates a new ballot entry in the database.
-func (ds *dynamoStore) PutBallot(ballot *models.Ballot) error { return storeItem(ds, ballot) }
+// EditPoll replaces a poll's prompt and choices in the database. It reports false without
+// changing anything if the poll no longer exists or a ballot has been cast in it, since ballots
+// refer to the choices by their indices.
+func (ds *dynamoStore) EditPoll(poll *models.Poll) (bool, error) {
+	// A poll without ballots has no stored ballot version
+	condition := fmt.Sprintf("attribute_exists(%s) AND attribute_not_exists(BallotVersion)",
+		pollsTableInfo.partitionKey)
+	return updatePollAttributes(ds, poll, condition, "Prompt", "Choices")
+}
+
+// SchedulePoll replaces when a poll opens and closes in the database, along with its
+// tie-breaking seed, which changes when a closed poll is reopened. It leaves the ballot version
+// alone so that ballots can still be cast, and reports false if the poll no longer exists.
+func (ds *dynamoStore) SchedulePoll(poll *models.Poll) (bool, error) {
+	condition := fmt.Sprintf("attribute_exists(%s)", pollsTableInfo.partitionKey)
+	return updatePollAttributes(ds, poll, condition, "OpensAt", "ClosesAt", "TieBreakSeed")
+}
+
+// updatePollAttributes sets the named attributes of a poll's entry to their values in the poll,
+// removing the ones the poll leaves out. It reports false without changing anything if the
+// condition fails.
+func updatePollAttributes(
+	ds *dynamoStore, poll *models.Poll, condition string, names ...string,
+) (bool, error) {
+	av, err := attributevalue.MarshalMap(poll)
+	if err != nil {
+		return false, err
+	}
+	var sets, removes []string
+	expAttNames := make(map[string]string, len(names))
+	expAttVals := make(map[string]types.AttributeValue)
+	for i, name := range names {
+		// Substitute every name, since some attribute names are reserved words
+		placeholder := fmt.Sprintf("#attr%d", i)
+		expAttNames[placeholder] = name
+		if value, ok := av[name]; ok {
+			sets = append(sets, fmt.Sprintf("%s = :val%d", placeholder, i))
+			expAttVals[fmt.Sprintf(":val%d", i)] = value
+		} else {
+			removes = append(removes, placeholder)
+		}
+	}
+	var clauses []string
+	if len(sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(sets, ", "))
+	}
+	if len(removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(removes, ", "))
+	}
+	// DynamoDB rejects empty expression attribute values
+	if len(expAttVals) == 0 {
+		expAttVals = nil
+	}
+	_, err = ds.client.UpdateItem(ds.ctx, &dynamodb.UpdateItemInput{
+		TableName: &pollsTableInfo.name,
+		Key: map[string]types.AttributeValue{
+			pollsTableInfo.partitionKey: &types.AttributeValueMemberS{Value: poll.ID()},
+		},
+		UpdateExpression:          utils.Ref(strings.Join(clauses, " ")),
+		ConditionExpression:       &condition,
+		ExpressionAttributeNames:  expAttNames,
+		ExpressionAttributeValues: expAttVals,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DeletePoll removes a poll's entry from the database along with all of its ballots and its tally
+// snapshot. The poll is removed after its ballots so that a failed deletion can be tried again
+// with the poll's admin token, and the ballots are removed once more afterward in case any were
+// cast in the meantime, since none can be cast once the poll is gone.
+func (ds *dynamoStore) DeletePoll(pollID string) error {
+	if err := ds.deleteBallotsAndTally(pollID); err != nil {
+		return err
+	}
+	_, err := ds.client.DeleteItem(ds.ctx, &dynamodb.DeleteItemInput{
+		TableName: &pollsTableInfo.name,
+		Key: map[string]types.AttributeValue{
+			pollsTableInfo.partitionKey: &types.AttributeValueMemberS{Value: pollID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return ds.deleteBallotsAndTally(pollID)
+}
+
+// deleteBallotsAndTally removes every item under the poll's partition in the Ballots and Tallies
+// tables.
+func (ds *dynamoStore) deleteBallotsAndTally(pollID string) error {
+	for _, table := range []*tableInfo{ballotsTableInfo, talliesTableInfo} {
+		// Only the keys are needed to delete the items
+		keys, err := queryAll(ds, &dynamodb.QueryInput{
+			TableName:              &table.name,
+			KeyConditionExpression: utils.Ref(fmt.Sprintf("%s = :pk", table.partitionKey)),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":pk": &types.AttributeValueMemberS{Value: pollID},
+			},
+			ProjectionExpression: utils.Ref(table.partitionKey + ", " + table.sortKey),
+			ConsistentRead:       utils.Ref(true),
+		})
+		if err != nil {
+			return err
+		}
+		requests := make([]types.WriteRequest, len(keys))
+		for i, key := range keys {
+			requests[i] = types.WriteRequest{DeleteRequest: &types.DeleteRequest{Key: key}}
+		}
+		if err = batchWrite(ds, table.name, requests); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PutBallot creates a new ballot entry in the database, or replaces the voter's previous ballot,
+// and increments the poll's ballot version. A new ballot is also added to the poll's tally
+// snapshot in the same transaction, while a replaced ballot leaves the snapshot out of date so
+// that it is rebuilt.
+func (ds *dynamoStore) PutBallot(ballot *models.Ballot) error {
+	av, err := attributevalue.MarshalMap(ballot)
+	if err != nil {
+		return err
+	}
+	// Only add the ballot to the tally if the voter has not cast one before
+	put := &types.Put{
+		TableName: &ballotsTableInfo.name,
+		Item:      av,
+		ConditionExpression: utils.Ref(
+			fmt.Sprintf("attribute_not_exists(%s)", ballotsTableInfo.sortKey)),
+	}
+	items := []types.TransactWriteItem{{Put: put}, {Update: incrementBallotVersion(ballot.PollID(), 1)}}
+	_, err = ds.client.TransactWriteItems(ds.ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append(items, tallyUpdates(ballot)...),
+	})
+	if !isConditionalCheckFailure(err, 0) {
+		return err
+	}
+	// The voter already cast a ballot, so replace it without updating the tally
+	put.ConditionExpression = nil
+	_, err = ds.client.TransactWriteItems(ds.ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	return err
+}
+
+// PutBallots creates entries in the database for a newly created poll's ballots in batches, and
+// then adds the number of ballots to the poll's ballot version. It leaves the poll's tally
+// snapshot unchanged, so the snapshot is built from the stored ballots when it is first needed.
+func (ds *dynamoStore) PutBallots(ballots []*models.Ballot) error {
+	if len(ballots) == 0 {
+		return nil
+	}
+	requests := make([]types.WriteRequest, len(ballots))
+	for i, ballot := range ballots {
+		av, err := attributevalue.MarshalMap(ballot)
+		if err != nil {
+			return err
+		}
+		requests[i] = types.WriteRequest{PutRequest: &types.PutRequest{Item: av}}
+	}
+	if err := batchWrite(ds, ballotsTableInfo.name, requests); err != nil {
+		return err
+	}
+	// The poll has ballots now, even though none were cast individually
+	update := incrementBallotVersion(ballots[0].PollID(), len(ballots))
+	_, err := ds.client.UpdateItem(ds.ctx, &dynamodb.UpdateItemInput{
+		TableName:                 update.TableName,
+		Key:                       update.Key,
+		UpdateExpression:          update.UpdateExpression,
+		ConditionExpression:       update.ConditionExpression,
+		ExpressionAttributeValues: update.ExpressionAttributeValues,
+	})
+	return err
+}
 
 // GetPoll retrieves a poll from the database by its poll ID.
 func (ds *dynamoStore) GetPoll(pollID string) (*models.Poll, error) {
@@ -44,7 +229,8 @@ func (ds *dynamoStore) GetPoll(pollID string) (*models.Poll, error) {
 	return retrieveItem[models.Poll](ds, key)
 }
 
-// GetBallots retrieves all of the ballots for the specified poll from the database.
+// GetBallots retrieves all of the ballots for the specified poll from the database, reading
+// every page of the query.
 func (ds *dynamoStore) GetBallots(pollID string) ([]*models.Ballot, error) {
 	// Define the key condition expression and expression attribute values to query by poll ID
 	keyConExp := utils.Ref(fmt.Sprintf("%s = :pk", pollsTableInfo.partitionKey))
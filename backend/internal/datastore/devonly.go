@@ -12,15 +12,18 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
-// EnsureBothLocalTablesExist ensure that both the Ballots and Polls tables exist for local
+// EnsureLocalTablesExist ensures that the Ballots, Polls, and Tallies tables exist for local
 // development purposes.
-func EnsureBothLocalTablesExist(client *dynamodb.Client) {
+func EnsureLocalTablesExist(client *dynamodb.Client) {
 	if !localTableExists(client, ballotsTableInfo) {
 		createLocalTable(client, ballotsTableInfo, createBallotsTableInput)
 	}
 	if !localTableExists(client, pollsTableInfo) {
 		createLocalTable(client, pollsTableInfo, createPollsTableInput)
 	}
+	if !localTableExists(client, talliesTableInfo) {
+		createLocalTable(client, talliesTableInfo, createTalliesTableInput)
+	}
 	printLocalTables(client)
 }
 
@@ -48,6 +51,19 @@ var createPollsTableInput = &dynamodb.CreateTableInput{
 	BillingMode: types.BillingModePayPerRequest,
 }
 
+var createTalliesTableInput = &dynamodb.CreateTableInput{
+	TableName: &talliesTableInfo.name,
+	AttributeDefinitions: []types.AttributeDefinition{
+		{AttributeName: &talliesTableInfo.partitionKey, AttributeType: types.ScalarAttributeTypeS},
+		{AttributeName: &talliesTableInfo.sortKey, AttributeType: types.ScalarAttributeTypeS},
+	},
+	KeySchema: []types.KeySchemaElement{
+		{AttributeName: &talliesTableInfo.partitionKey, KeyType: types.KeyTypeHash},
+		{AttributeName: &talliesTableInfo.sortKey, KeyType: types.KeyTypeRange},
+	},
+	BillingMode: types.BillingModePayPerRequest,
+}
+
 // localTableExists checks if the specified table exists in the local DynamoDB in Docker.
 func localTableExists(client *dynamodb.Client, table *tableInfo) bool {
 	_, err := client.DescribeTable(context.TODO(),
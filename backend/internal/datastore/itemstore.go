@@ -1,6 +1,10 @@
 package datastore
 
 import (
+	"errors"
+	"slices"
+	"time"
+
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -55,14 +59,73 @@ func retrieveItems[T tableModel](
 ) ([]*T, error) {
 	var out []*T
 	// Query the database using the provided KCE and EAV
-	dbOut, err := ds.client.Query(ds.ctx, &dynamodb.QueryInput{
+	items, err := queryAll(ds, &dynamodb.QueryInput{
 		TableName:                 tableNameFor(new(T)),
 		KeyConditionExpression:    keyConExp,
 		ExpressionAttributeValues: expAttVals,
+		ConsistentRead:            utils.Ref(true),
 	})
 	if err == nil {
 		// Unmarshal the result into the slice to return
-		err = attributevalue.UnmarshalListOfMaps(dbOut.Items, &out)
+		err = attributevalue.UnmarshalListOfMaps(items, &out)
 	}
 	return out, err
 }
+
+// queryAll runs the query and collects the items from every page of results.
+func queryAll(
+	ds *dynamoStore, input *dynamodb.QueryInput,
+) ([]map[string]types.AttributeValue, error) {
+	var items []map[string]types.AttributeValue
+	for {
+		dbOut, err := ds.client.Query(ds.ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, dbOut.Items...)
+		if len(dbOut.LastEvaluatedKey) == 0 {
+			return items, nil
+		}
+		// Continue from where the previous page stopped
+		input.ExclusiveStartKey = dbOut.LastEvaluatedKey
+	}
+}
+
+// maxBatchWriteAttempts limits how many times a batch is written before its unprocessed requests
+// are given up on, and batchWriteBackoff is the wait before the first retry, which doubles with
+// each retry after it.
+const (
+	maxBatchWriteAttempts = 6
+	batchWriteBackoff     = 20 * time.Millisecond
+)
+
+// batchWrite performs the write requests on the table in batches of the largest size DynamoDB
+// allows, retrying any requests that were not processed with exponential backoff.
+func batchWrite(ds *dynamoStore, tableName string, requests []types.WriteRequest) error {
+	for batch := range slices.Chunk(requests, 25) {
+		pending := map[string][]types.WriteRequest{tableName: batch}
+		backoff := batchWriteBackoff
+		for attempt := 0; len(pending[tableName]) > 0; attempt++ {
+			if attempt == maxBatchWriteAttempts {
+				return errors.New("the database did not process every write in time")
+			}
+			if attempt > 0 {
+				// Requests go unprocessed when the table is throttled, so give it time to recover
+				select {
+				case <-ds.ctx.Done():
+					return ds.ctx.Err()
+				case <-time.After(backoff):
+				}
+				backoff *= 2
+			}
+			dbOut, err := ds.client.BatchWriteItem(ds.ctx, &dynamodb.BatchWriteItemInput{
+				RequestItems: pending,
+			})
+			if err != nil {
+				return err
+			}
+			pending = dbOut.UnprocessedItems
+		}
+	}
+	return nil
+}
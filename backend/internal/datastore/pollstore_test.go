@@ -3,9 +3,14 @@ package datastore_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/noahkawaguchi/verdict/backend/internal/datastore"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
 )
@@ -69,3 +74,150 @@ func TestGetPoll_Success(t *testing.T) {
 		t.Error("expected success, got:", err)
 	}
 }
+
+func TestEditPoll(t *testing.T) {
+	tests := []struct {
+		updateErr error
+		updated   bool
+		errMsg    string
+	}{
+		{nil, true, ""},
+		{&types.ConditionalCheckFailedException{}, false, ""},
+		{errors.New("mocked error"), false, "mocked error"},
+	}
+	for _, test := range tests {
+		var input *dynamodb.UpdateItemInput
+		tableStore := datastore.New(context.TODO(), &mockDynamo{
+			UpdateItemMock: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+				input = params
+				return &dynamodb.UpdateItemOutput{}, test.updateErr
+			},
+		})
+		poll := models.NewPoll("What is the best int size?", []string{"32", "64"})
+		updated, err := tableStore.EditPoll(poll)
+		if updated != test.updated || (err == nil) != (test.errMsg == "") ||
+			(err != nil && err.Error() != test.errMsg) {
+			t.Errorf("unexpected result: %t, %v", updated, err)
+		}
+		// Only the prompt and choices are set, and only while the poll has no ballots
+		if *input.UpdateExpression != "SET #attr0 = :val0, #attr1 = :val1" ||
+			input.ExpressionAttributeNames["#attr1"] != "Choices" ||
+			*input.ConditionExpression !=
+				"attribute_exists(PollID) AND attribute_not_exists(BallotVersion)" {
+			t.Errorf("unexpected update: %s if %s",
+				*input.UpdateExpression, *input.ConditionExpression)
+		}
+	}
+}
+
+func TestSchedulePoll(t *testing.T) {
+	var input *dynamodb.UpdateItemInput
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		UpdateItemMock: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+			input = params
+			return &dynamodb.UpdateItemOutput{}, nil
+		},
+	})
+	// The poll closes but has no opening time, which must be removed rather than set
+	poll := models.NewPoll("What is the best int size?", []string{"32", "64"})
+	poll.Close(time.Date(2025, time.March, 8, 17, 0, 0, 0, time.UTC))
+	updated, err := tableStore.SchedulePoll(poll)
+	if !updated || err != nil {
+		t.Fatalf("unexpected result: %t, %v", updated, err)
+	}
+	// The ballot version is neither set nor checked, so ballots can be cast meanwhile
+	if *input.UpdateExpression != "SET #attr1 = :val1, #attr2 = :val2 REMOVE #attr0" ||
+		input.ExpressionAttributeNames["#attr0"] != "OpensAt" ||
+		*input.ConditionExpression != "attribute_exists(PollID)" {
+		t.Errorf("unexpected update: %s if %s",
+			*input.UpdateExpression, *input.ConditionExpression)
+	}
+	closesAt, _ := input.ExpressionAttributeValues[":val1"].(*types.AttributeValueMemberS)
+	if closesAt == nil || closesAt.Value != "2025-03-08T17:00:00Z" {
+		t.Error("unexpected closing time:", input.ExpressionAttributeValues)
+	}
+}
+
+func TestDeletePoll(t *testing.T) {
+	keys := func(table string, count int) []map[string]types.AttributeValue {
+		items := make([]map[string]types.AttributeValue, count)
+		for i := range items {
+			items[i] = map[string]types.AttributeValue{
+				"PollID": &types.AttributeValueMemberS{Value: "poll1"},
+				table:    &types.AttributeValueMemberS{Value: strconv.Itoa(i)},
+			}
+		}
+		return items
+	}
+	var operations []string
+	deleted := false
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			operations = append(operations, "query "+*params.TableName)
+			switch {
+			case deleted && *params.TableName == "Ballots":
+				// A ballot was cast while the poll was being deleted
+				return &dynamodb.QueryOutput{Items: keys("UserID", 1)}, nil
+			case deleted:
+				return &dynamodb.QueryOutput{}, nil
+			case *params.TableName == "Tallies":
+				return &dynamodb.QueryOutput{Items: keys("RankKey", 2)}, nil
+			case params.ExclusiveStartKey == nil:
+				// The ballots are read in two pages
+				return &dynamodb.QueryOutput{
+					Items: keys("UserID", 30), LastEvaluatedKey: keys("UserID", 1)[0],
+				}, nil
+			default:
+				return &dynamodb.QueryOutput{Items: keys("UserID", 3)}, nil
+			}
+		},
+		BatchWriteItemMock: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			for table, requests := range params.RequestItems {
+				for _, request := range requests {
+					if request.DeleteRequest == nil {
+						t.Error("expected only delete requests")
+					}
+				}
+				operations = append(operations,
+					fmt.Sprintf("delete %d from %s", len(requests), table))
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+		DeleteItemMock: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			pollID, ok := params.Key["PollID"].(*types.AttributeValueMemberS)
+			if *params.TableName != "Polls" || !ok || pollID.Value != "poll1" {
+				t.Error("unexpected poll deletion:", params)
+			}
+			operations = append(operations, "delete poll")
+			deleted = true
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	})
+	if err := tableStore.DeletePoll("poll1"); err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	expected := []string{
+		"query Ballots", "query Ballots", "delete 25 from Ballots", "delete 8 from Ballots",
+		"query Tallies", "delete 2 from Tallies",
+		"delete poll",
+		"query Ballots", "delete 1 from Ballots", "query Tallies",
+	}
+	if !slices.Equal(operations, expected) {
+		t.Error("unexpected operations:", operations)
+	}
+}
+
+func TestDeletePoll_Error(t *testing.T) {
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("mocked error")
+		},
+		DeleteItemMock: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+			t.Error("expected the poll to be kept when its ballots cannot be deleted")
+			return &dynamodb.DeleteItemOutput{}, nil
+		},
+	})
+	if err := tableStore.DeletePoll("poll1"); err == nil || err.Error() != "mocked error" {
+		t.Error(`expected "mocked error", got:`, err)
+	}
+}
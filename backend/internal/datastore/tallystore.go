@@ -0,0 +1,184 @@
+package datastore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
+)
+
+// versionRankKey is the sort key of the item holding a tally snapshot's version. It cannot
+// collide with the hash of a ballot's rank key, which is always hexadecimal.
+const versionRankKey = "#version"
+
+// tallyItem is a single item of a tally snapshot, which is either the count for a rank key or
+// the snapshot's version. The sort key of a count is the hash of its rank key, since rank keys
+// grow with the number of choices and write-ins but DynamoDB limits sort keys to 1024 bytes. The
+// rank key itself is stored as the ballot that is counted.
+type tallyItem struct {
+	PollID, RankKey string
+	Ballot          string `dynamodbav:",omitempty"`
+	Count           int    `dynamodbav:",omitempty"`
+	Version         int    `dynamodbav:",omitempty"`
+	Built           bool   `dynamodbav:",omitempty"`
+}
+
+// hashRankKey creates the fixed-size sort key for the count of a rank key.
+func hashRankKey(rankKey string) string {
+	hash := sha256.Sum256([]byte(rankKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetTally retrieves the tally snapshot for the specified poll from the database. It returns
+// nil if the snapshot has never been built, even if ballots have been added to it since.
+func (ds *dynamoStore) GetTally(pollID string) (*models.Tally, error) {
+	items, err := queryAll(ds, &dynamodb.QueryInput{
+		TableName:              &talliesTableInfo.name,
+		KeyConditionExpression: utils.Ref(fmt.Sprintf("%s = :pk", talliesTableInfo.partitionKey)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: pollID},
+		},
+		ConsistentRead: utils.Ref(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var tallyItems []tallyItem
+	if err = attributevalue.UnmarshalListOfMaps(items, &tallyItems); err != nil {
+		return nil, err
+	}
+	version, built, counts := 0, false, make(map[string]int)
+	for _, item := range tallyItems {
+		switch {
+		case item.RankKey == versionRankKey:
+			version, built = item.Version, item.Built
+		case item.Count > 0: // Skip rank keys left over from previous snapshots
+			counts[item.Ballot] = item.Count
+		}
+	}
+	if !built {
+		return nil, nil
+	}
+	return models.NewTally(pollID, version, counts), nil
+}
+
+// PutTally replaces the tally snapshot for the tally's poll in the database. The counts are
+// written before the version so that the snapshot is never marked as built with missing counts.
+func (ds *dynamoStore) PutTally(tally *models.Tally) error {
+	// Find the rank keys of the previous snapshot, which must be zeroed if they are not in the
+	// new one
+	previous, err := queryAll(ds, &dynamodb.QueryInput{
+		TableName:              &talliesTableInfo.name,
+		KeyConditionExpression: utils.Ref(fmt.Sprintf("%s = :pk", talliesTableInfo.partitionKey)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":pk": &types.AttributeValueMemberS{Value: tally.PollID()},
+		},
+		ProjectionExpression: &talliesTableInfo.sortKey,
+	})
+	if err != nil {
+		return err
+	}
+	items := make(map[string]tallyItem, len(tally.Counts()))
+	for rankKey, count := range tally.Counts() {
+		hash := hashRankKey(rankKey)
+		items[hash] = tallyItem{
+			PollID: tally.PollID(), RankKey: hash, Ballot: rankKey, Count: count,
+		}
+	}
+	for _, item := range previous {
+		var sortKey string
+		if err = attributevalue.Unmarshal(item[talliesTableInfo.sortKey], &sortKey); err != nil {
+			return err
+		}
+		if _, ok := items[sortKey]; !ok && sortKey != versionRankKey {
+			items[sortKey] = tallyItem{PollID: tally.PollID(), RankKey: sortKey}
+		}
+	}
+	var requests []types.WriteRequest
+	for _, item := range items {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			return err
+		}
+		requests = append(requests, types.WriteRequest{PutRequest: &types.PutRequest{Item: av}})
+	}
+	if err = batchWrite(ds, talliesTableInfo.name, requests); err != nil {
+		return err
+	}
+	av, err := attributevalue.MarshalMap(tallyItem{
+		PollID: tally.PollID(), RankKey: versionRankKey, Version: tally.Version(), Built: true,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = ds.client.PutItem(ds.ctx, &dynamodb.PutItemInput{
+		TableName: &talliesTableInfo.name, Item: av,
+	})
+	return err
+}
+
+// incrementBallotVersion creates the update that adds the number of new ballots to a poll's
+// ballot version, which fails if the poll does not exist.
+func incrementBallotVersion(pollID string, count int) *types.Update {
+	return &types.Update{
+		TableName: &pollsTableInfo.name,
+		Key: map[string]types.AttributeValue{
+			pollsTableInfo.partitionKey: &types.AttributeValueMemberS{Value: pollID},
+		},
+		UpdateExpression: utils.Ref("ADD BallotVersion :count"),
+		ConditionExpression: utils.Ref(
+			fmt.Sprintf("attribute_exists(%s)", pollsTableInfo.partitionKey)),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":count": &types.AttributeValueMemberN{Value: strconv.Itoa(count)},
+		},
+	}
+}
+
+// tallyUpdates creates the updates that add a new ballot to its poll's tally snapshot. The
+// snapshot's version is incremented along with the poll's ballot version, so the two stay equal
+// only if they were equal before.
+func tallyUpdates(ballot *models.Ballot) []types.TransactWriteItem {
+	update := func(sortKey, attribute string) *types.Update {
+		return &types.Update{
+			TableName: &talliesTableInfo.name,
+			Key: map[string]types.AttributeValue{
+				talliesTableInfo.partitionKey: &types.AttributeValueMemberS{
+					Value: ballot.PollID(),
+				},
+				talliesTableInfo.sortKey: &types.AttributeValueMemberS{Value: sortKey},
+			},
+			// Count is a reserved word, so the attribute name must be substituted
+			UpdateExpression:         utils.Ref("ADD #attr :one"),
+			ExpressionAttributeNames: map[string]string{"#attr": attribute},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":one": &types.AttributeValueMemberN{Value: "1"},
+			},
+		}
+	}
+	// The count's item also records the rank key that its sort key is the hash of
+	rankKey := ballot.RankKey()
+	count := update(hashRankKey(rankKey), "Count")
+	count.UpdateExpression = utils.Ref("ADD #attr :one SET Ballot = :ballot")
+	count.ExpressionAttributeValues[":ballot"] = &types.AttributeValueMemberS{Value: rankKey}
+	return []types.TransactWriteItem{
+		{Update: count}, {Update: update(versionRankKey, "Version")},
+	}
+}
+
+// isConditionalCheckFailure reports whether the error is a cancelled transaction whose item at
+// the index failed its condition.
+func isConditionalCheckFailure(err error, index int) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || len(canceled.CancellationReasons) <= index {
+		return false
+	}
+	code := canceled.CancellationReasons[index].Code
+	return code != nil && *code == "ConditionalCheckFailed"
+}
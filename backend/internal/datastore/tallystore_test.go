@@ -0,0 +1,152 @@
+package datastore_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/datastore"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func tallyItems(t *testing.T, items ...map[string]any) []map[string]types.AttributeValue {
+	out := make([]map[string]types.AttributeValue, len(items))
+	for i, item := range items {
+		av, err := attributevalue.MarshalMap(item)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		out[i] = av
+	}
+	return out
+}
+
+func TestGetTally_Error(t *testing.T) {
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			return nil, errors.New("mocked error")
+		},
+	})
+	if _, err := tableStore.GetTally("any poll"); err == nil || err.Error() != "mocked error" {
+		t.Error(`expected "mocked error", got:`, err)
+	}
+}
+
+func TestGetTally_NotBuilt(t *testing.T) {
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			// A ballot was added before the snapshot was ever built
+			return &dynamodb.QueryOutput{Items: tallyItems(t,
+				map[string]any{"PollID": "poll1", "RankKey": "#version", "Version": 1},
+				map[string]any{"PollID": "poll1", "RankKey": "a1", "Count": 1,
+					"Ballot": `{"rankOrder":[0,1]}`},
+			)}, nil
+		},
+	})
+	tally, err := tableStore.GetTally("poll1")
+	if err != nil || tally != nil {
+		t.Errorf("expected no tally, got %v and %v", tally, err)
+	}
+}
+
+func TestGetTally_Success(t *testing.T) {
+	pages := [][]map[string]types.AttributeValue{
+		tallyItems(t,
+			map[string]any{"PollID": "poll1", "RankKey": "#version", "Version": 7, "Built": true},
+			map[string]any{"PollID": "poll1", "RankKey": "a1", "Count": 4,
+				"Ballot": `{"rankOrder":[0,1]}`},
+		),
+		tallyItems(t,
+			map[string]any{"PollID": "poll1", "RankKey": "b2", "Count": 3,
+				"Ballot": `{"rankOrder":[1,0]}`},
+			map[string]any{"PollID": "poll1", "RankKey": "c3"},
+		),
+	}
+	calls := 0
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			calls++
+			out := &dynamodb.QueryOutput{Items: pages[calls-1]}
+			if calls < len(pages) {
+				out.LastEvaluatedKey = pages[calls-1][1]
+			}
+			return out, nil
+		},
+	})
+	tally, err := tableStore.GetTally("poll1")
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	if calls != 2 || tally.Version() != 7 {
+		t.Errorf("unexpected calls or version: %d, %d", calls, tally.Version())
+	}
+	expected := map[string]int{`{"rankOrder":[0,1]}`: 4, `{"rankOrder":[1,0]}`: 3}
+	if !cmp.Equal(tally.Counts(), expected) {
+		t.Error("unexpected counts:", tally.Counts())
+	}
+}
+
+func TestPutTally(t *testing.T) {
+	var written []map[string]types.AttributeValue
+	tableStore := datastore.New(context.TODO(), &mockDynamo{
+		QueryMock: func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+			// The previous snapshot had a rank key that no ballot has anymore
+			return &dynamodb.QueryOutput{Items: tallyItems(t,
+				map[string]any{"RankKey": "#version"},
+				map[string]any{"RankKey": "stale"},
+			)}, nil
+		},
+		BatchWriteItemMock: func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+			for _, request := range params.RequestItems["Tallies"] {
+				written = append(written, request.PutRequest.Item)
+			}
+			return &dynamodb.BatchWriteItemOutput{}, nil
+		},
+		PutItemMock: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			written = append(written, params.Item)
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	})
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"})
+	tally := models.TallyOf(poll, []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{0, 1}),
+	})
+	if err := tableStore.PutTally(tally); err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	var items []struct {
+		RankKey, Ballot string
+		Count, Version  int
+		Built           bool
+	}
+	if err := attributevalue.UnmarshalListOfMaps(written, &items); err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(items) != 3 {
+		t.Fatalf("unexpected items: %+v", items)
+	}
+	// Counts are stored under the hashes of their rank keys, and the stale count is zeroed
+	hash := sha256.Sum256([]byte(`{"rankOrder":[0,1]}`))
+	counts := make(map[string]int)
+	for _, item := range items[:2] {
+		counts[item.RankKey+" "+item.Ballot] = item.Count
+	}
+	expected := map[string]int{
+		hex.EncodeToString(hash[:]) + ` {"rankOrder":[0,1]}`: 2,
+		"stale ": 0,
+	}
+	if !cmp.Equal(counts, expected) {
+		t.Error("unexpected counts:", counts)
+	}
+	// The version is written last
+	if items[2].RankKey != "#version" || !items[2].Built {
+		t.Errorf("unexpected version item: %+v", items[2])
+	}
+}
@@ -13,36 +13,157 @@ import (
 
 type Ballot struct {
 	pollID, userID string
-	// The indices of the voter's choices. For example, if the voter's first choice is at index 2
-	// in the poll's choices, then rankOrder[0] = 2.
-	rankOrder []int
+	// The indices of the voter's choices, grouped into tiers of equally ranked choices. For
+	// example, if the voter's only first choice is at index 2 in the poll's choices, then
+	// rankOrder[0] = [2].
+	rankOrder []rankTier
+	// The names the voter wrote in, if the poll allows write-ins. Indices in the rank order past
+	// the end of the poll's choices refer to these, so len(choices) refers to writeIns[0].
+	writeIns []string
+	// The weight of the voter on the poll's voter roll, where zero means one. It is set by the
+	// poll rather than by the voter.
+	weight float64
+	// The number of identical ballots this ballot stands for, where zero means one. It is only
+	// more than one for ballots recreated from a tally, so that they are not recreated one by one.
+	count int
 }
 
+// rankTier holds the indices of the choices a voter ranked equally. It is represented as a
+// single index instead of a list when it holds one choice, which is the only form ballots had
+// before equal rankings were allowed.
+type rankTier []int
+
+// NewBallot creates a ballot with a strict rank order, where rankOrder[0] is the index of the
+// voter's first choice.
 func NewBallot(pollID, userID string, rankOrder []int) *Ballot {
-	return &Ballot{pollID, userID, rankOrder}
+	var tiers []rankTier
+	for _, choiceIdx := range rankOrder {
+		tiers = append(tiers, rankTier{choiceIdx})
+	}
+	return &Ballot{pollID: pollID, userID: userID, rankOrder: tiers}
+}
+
+// NewTieredBallot creates a ballot that can rank choices equally, where rankOrder[0] holds the
+// indices of the voter's first choices.
+func NewTieredBallot(pollID, userID string, rankOrder [][]int) *Ballot {
+	var tiers []rankTier
+	for _, tier := range rankOrder {
+		tiers = append(tiers, tier)
+	}
+	return &Ballot{pollID: pollID, userID: userID, rankOrder: tiers}
+}
+
+// NewWriteInBallot creates a ballot that ranks names written in by the voter as well as the
+// poll's choices, where the index len(choices)+i in rankOrder refers to writeIns[i].
+func NewWriteInBallot(pollID, userID string, rankOrder [][]int, writeIns []string) *Ballot {
+	b := NewTieredBallot(pollID, userID, rankOrder)
+	b.writeIns = writeIns
+	return b
+}
+
+// PollID gets the ID of the poll the ballot is for.
+func (b *Ballot) PollID() string { return b.pollID }
+
+// Weight gets the number of votes the ballot counts as, defaulting to one.
+func (b *Ballot) Weight() float64 {
+	if b.weight == 0 {
+		return 1
+	}
+	return b.weight
+}
+
+// numBallots gets the number of identical ballots the ballot stands for, defaulting to one.
+func (b *Ballot) numBallots() int { return max(b.count, 1) }
+
+// value gets the combined weight of the identical ballots the ballot stands for.
+func (b *Ballot) value() float64 { return b.Weight() * float64(b.numBallots()) }
+
+// totalVotes adds up the weights of the ballots, including every identical ballot each one
+// stands for, which is the total that every weighted vote count is out of.
+func totalVotes(ballots []*Ballot) float64 {
+	total := 0.0
+	for _, ballot := range ballots {
+		total += ballot.value()
+	}
+	return total
+}
+
+// ValidateFor ensures that none of the fields are empty, that the voter is on the poll's voter
+// roll if it has one, and that the rank order and write-ins are valid for the provided poll.
+// Unless the poll allows partial rankings, every choice must be ranked exactly once. Otherwise,
+// at least the poll's minimum number of choices must be ranked, none of them more than once. In
+// either case, no tier of equally ranked choices can be empty, and every write-in must be ranked.
+func (b *Ballot) ValidateFor(poll *Poll) error {
+	if err := b.validateIDs(); err != nil {
+		return err
+	}
+	if _, ok := poll.voterRoll[b.userID]; len(poll.voterRoll) > 0 && !ok {
+		return errors.New("only voters on the poll's voter roll can vote")
+	}
+	if err := b.validateWriteIns(poll); err != nil {
+		return err
+	}
+	ranked := b.rankedChoices()
+	if minRanks := poll.MinRanks(); len(ranked) < minRanks {
+		if minRanks == 1 {
+			return errors.New("there must be at least one ranking")
+		}
+		return fmt.Errorf("there must be at least %d rankings", minRanks)
+	}
+	if slices.ContainsFunc(b.rankOrder, func(tier rankTier) bool { return len(tier) == 0 }) {
+		return errors.New("not a valid rank order")
+	}
+	seen := make([]bool, len(poll.choices)+len(b.writeIns))
+	for _, choiceIdx := range ranked {
+		if choiceIdx < 0 || choiceIdx >= len(seen) || seen[choiceIdx] {
+			return errors.New("not a valid rank order")
+		}
+		seen[choiceIdx] = true
+	}
+	// Ranking write-ins does not make up for leaving the poll's own choices unranked
+	if !poll.settings.AllowPartial && slices.Contains(seen[:len(poll.choices)], false) {
+		return errors.New("not a valid rank order")
+	}
+	if slices.Contains(seen[len(poll.choices):], false) {
+		return errors.New("every write-in must be ranked")
+	}
+	return nil
+}
+
+// rankedChoices flattens the tiers of the rank order into a new slice of the ranked choices'
+// indices.
+func (b *Ballot) rankedChoices() []int {
+	var ranked []int
+	for _, tier := range b.rankOrder {
+		ranked = append(ranked, tier...)
+	}
+	return ranked
 }
 
-// Validate ensures that none of the fields are empty, there are at least two rankings, and the
-// rank order is a permutation of its indices.
-func (b *Ballot) Validate() error {
+// validateIDs ensures that neither the poll ID nor the user ID is empty.
+func (b *Ballot) validateIDs() error {
 	if b.pollID == "" {
 		return errors.New("poll ID cannot be empty")
 	}
 	if b.userID == "" {
 		return errors.New("user ID cannot be empty")
 	}
-	if len(b.rankOrder) < 2 {
-		return errors.New("there must be at least two rankings")
+	return nil
+}
+
+// tierIndices finds the index of the tier that ranks each of the poll's choices, where unranked
+// choices share a tier after the last one.
+func (b *Ballot) tierIndices(numChoices int) []int {
+	tierOf := make([]int, numChoices)
+	for choiceIdx := range tierOf {
+		tierOf[choiceIdx] = len(b.rankOrder)
 	}
-	// Copy the slice to avoid changing the original underlying array
-	sortCopy := slices.Clone(b.rankOrder)
-	slices.Sort(sortCopy)
-	for i, v := range sortCopy {
-		if v != i {
-			return errors.New("not a valid rank order")
+	for i, tier := range b.rankOrder {
+		for _, choiceIdx := range tier {
+			tierOf[choiceIdx] = i
 		}
 	}
-	return nil
+	return tierOf
 }
 
 func (b *Ballot) String() string {
@@ -54,9 +175,10 @@ func (b *Ballot) String() string {
 func (b *Ballot) UnmarshalJSON(data []byte) error {
 	// Create an auxiliary struct with exported fields to unmarshal the data
 	var aux struct {
-		PollID    string `json:"pollId"`
-		UserID    string `json:"userId"`
-		RankOrder []int  `json:"rankOrder"`
+		PollID    string     `json:"pollId"`
+		UserID    string     `json:"userId"`
+		RankOrder []rankTier `json:"rankOrder"`
+		WriteIns  []string   `json:"writeIns"`
 	}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
@@ -68,7 +190,7 @@ func (b *Ballot) UnmarshalJSON(data []byte) error {
 		b.userID = aux.UserID
 	}
 	// Set the other unmarshaled values back to the main struct
-	b.pollID, b.rankOrder = aux.PollID, aux.RankOrder
+	b.pollID, b.rankOrder, b.writeIns = aux.PollID, aux.RankOrder, aux.WriteIns
 	return nil
 }
 
@@ -77,8 +199,9 @@ func (b *Ballot) UnmarshalJSON(data []byte) error {
 func (b *Ballot) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
 	m, err := attributevalue.MarshalMap(struct {
 		PollID, UserID string
-		RankOrder      []int
-	}{b.pollID, b.userID, b.rankOrder})
+		RankOrder      []rankTier
+		WriteIns       []string `dynamodbav:",omitempty"`
+	}{b.pollID, b.userID, b.rankOrder, b.writeIns})
 	if err != nil {
 		return nil, err
 	}
@@ -96,7 +219,8 @@ func (b *Ballot) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error
 	// Create a struct for custom unmarshaling
 	var aux struct {
 		PollID, UserID string
-		RankOrder      []int
+		RankOrder      []rankTier
+		WriteIns       []string
 	}
 	// Try to unmarshal using the custom struct
 	if err := attributevalue.UnmarshalMap(m.Value, &aux); err != nil {
@@ -104,5 +228,59 @@ func (b *Ballot) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error
 	}
 	// Set the unmarshaled values back to the main struct
 	b.pollID, b.userID, b.rankOrder = aux.PollID, aux.UserID, aux.RankOrder
+	b.writeIns = aux.WriteIns
+	return nil
+}
+
+// MarshalJSON is a custom marshaler that represents a tier holding one choice as just its index.
+func (t rankTier) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]int(t))
+}
+
+// UnmarshalJSON is a custom JSON unmarshaler that accepts either a single choice index or a list
+// of equally ranked choice indices.
+func (t *rankTier) UnmarshalJSON(data []byte) error {
+	var choiceIdx int
+	if err := json.Unmarshal(data, &choiceIdx); err == nil {
+		*t = rankTier{choiceIdx}
+		return nil
+	}
+	var tier []int
+	if err := json.Unmarshal(data, &tier); err != nil {
+		return errors.New("each rank must be a choice index or a list of choice indices")
+	}
+	*t = tier
+	return nil
+}
+
+// MarshalDynamoDBAttributeValue is a custom marshaler that stores a tier holding one choice as
+// just its index, so that strict ballots are stored the same way as before equal rankings were
+// allowed.
+func (t rankTier) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
+	if len(t) == 1 {
+		return attributevalue.Marshal(t[0])
+	}
+	return attributevalue.Marshal([]int(t))
+}
+
+// UnmarshalDynamoDBAttributeValue is a custom unmarshaler that accepts either a single choice
+// index or a list of equally ranked choice indices.
+func (t *rankTier) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	if _, ok := av.(*types.AttributeValueMemberN); ok {
+		var choiceIdx int
+		if err := attributevalue.Unmarshal(av, &choiceIdx); err != nil {
+			return err
+		}
+		*t = rankTier{choiceIdx}
+		return nil
+	}
+	var tier []int
+	if err := attributevalue.Unmarshal(av, &tier); err != nil {
+		return err
+	}
+	*t = tier
 	return nil
 }
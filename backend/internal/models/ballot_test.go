@@ -5,12 +5,15 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
 )
 
 func TestValidateBallot_Invalid(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine", "durian", "elderberry"})
 	tests := []struct {
 		errMsg    string
 		pollID    string
@@ -20,7 +23,7 @@ func TestValidateBallot_Invalid(t *testing.T) {
 		{
 			errMsg:    "poll ID cannot be empty",
 			userID:    "user1",
-			rankOrder: []int{0, 1, 2},
+			rankOrder: []int{0, 1, 2, 3, 4},
 		},
 		{
 			errMsg: "poll ID cannot be empty",
@@ -28,47 +31,134 @@ func TestValidateBallot_Invalid(t *testing.T) {
 		},
 		{
 			errMsg:    "poll ID cannot be empty",
-			rankOrder: []int{0, 1, 2},
+			rankOrder: []int{0, 1, 2, 3, 4},
 		},
 		{
-			errMsg:    "poll ID cannot be empty",
-			pollID:    "",
-			userID:    "user1",
-			rankOrder: []int{0, 1, 2},
+			errMsg:    "user ID cannot be empty",
+			pollID:    "poll1",
+			rankOrder: []int{0, 1, 2, 3, 4},
 		},
 		{
-			errMsg: "there must be at least two rankings",
+			errMsg: "there must be at least 5 rankings",
 			pollID: "poll3",
 			userID: "user3",
 		},
-		{"there must be at least two rankings", "poll2", "user2", []int{0}},
-		{"there must be at least two rankings", "poll2", "user2", []int{}},
+		{"there must be at least 5 rankings", "poll2", "user2", []int{0}},
+		{"there must be at least 5 rankings", "poll2", "user2", []int{}},
 		{"not a valid rank order", "poll2", "user2", []int{3, 5, 1, 2, 4}},
-		{"not a valid rank order", "poll3", "user3", []int{0, 1, 1, 1}},
+		{"not a valid rank order", "poll3", "user3", []int{0, 1, 1, 1, 1}},
 	}
 	for _, test := range tests {
 		ballot := models.NewBallot(test.pollID, test.userID, test.rankOrder)
-		if err := ballot.Validate(); err == nil || err.Error() != test.errMsg {
+		if err := ballot.ValidateFor(poll); err == nil || err.Error() != test.errMsg {
 			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
 		}
 	}
 }
 
 func TestValidateBallot_Valid(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine", "durian", "elderberry"})
 	tests := []struct {
 		pollID    string
 		userID    string
 		rankOrder []int
 	}{
-		{"poll1", "user1", []int{0, 1, 2}},
+		{"poll1", "user1", []int{0, 1, 2, 3, 4}},
 		{"poll2", "user2", []int{3, 0, 1, 2, 4}},
 		{"poll2", "user4", []int{4, 1, 0, 3, 2}},
 	}
 	for _, test := range tests {
 		ballot := models.NewBallot(test.pollID, test.userID, test.rankOrder)
-		if err := ballot.Validate(); err != nil {
+		if err := ballot.ValidateFor(poll); err != nil {
+			t.Errorf("expected success, got %v", err)
+		}
+	}
+}
+
+func TestValidateBallotFor(t *testing.T) {
+	choices := []string{"apple", "banana", "clementine", "durian"}
+	full := models.NewPoll("What is the best fruit?", choices)
+	partial := models.NewPoll("What is the best fruit?", choices, models.WithPartialRankings(2))
+	rolled := models.NewPoll("What is the best fruit?", choices,
+		models.WithVoterRoll(map[string]float64{"user1": 3}))
+	tests := []struct {
+		errMsg    string
+		poll      *models.Poll
+		userID    string
+		rankOrder []int
+	}{
+		{"user ID cannot be empty", full, "", []int{0, 1, 2, 3}},
+		{"there must be at least 4 rankings", full, "user1", []int{0, 1, 2}},
+		{"not a valid rank order", full, "user1", []int{0, 1, 2, 4}},
+		{"there must be at least 2 rankings", partial, "user1", []int{3}},
+		{"not a valid rank order", partial, "user1", []int{3, 3}},
+		{"not a valid rank order", partial, "user1", []int{3, -1, 0}},
+		{"", full, "user1", []int{3, 1, 2, 0}},
+		{"", partial, "user1", []int{3, 1}},
+		{"", partial, "user1", []int{3, 1, 2, 0}},
+		{"only voters on the poll's voter roll can vote", rolled, "user2", []int{3, 1, 2, 0}},
+		{"", rolled, "user1", []int{3, 1, 2, 0}},
+	}
+	for _, test := range tests {
+		ballot := models.NewBallot(test.poll.ID(), test.userID, test.rankOrder)
+		err := ballot.ValidateFor(test.poll)
+		if test.errMsg == "" && err != nil {
 			t.Errorf("expected success, got %v", err)
 		}
+		if test.errMsg != "" && (err == nil || err.Error() != test.errMsg) {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
+}
+
+func TestValidateBallot_EqualRankings(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana", "clementine"})
+	tests := []struct {
+		errMsg    string
+		rankOrder [][]int
+	}{
+		{"there must be at least 3 rankings", [][]int{{0}}},
+		{"not a valid rank order", [][]int{{0, 1}, {}, {2}}},
+		{"not a valid rank order", [][]int{{0, 1}, {1, 2}}},
+		{"there must be at least 3 rankings", [][]int{{0, 1}}},
+		{"", [][]int{{0, 1, 2}}},
+		{"", [][]int{{2}, {0, 1}}},
+		{"", [][]int{{2}, {0}, {1}}},
+	}
+	for _, test := range tests {
+		ballot := models.NewTieredBallot("poll1", "user1", test.rankOrder)
+		err := ballot.ValidateFor(poll)
+		if test.errMsg == "" && err != nil {
+			t.Errorf("expected success, got %v", err)
+		}
+		if test.errMsg != "" && (err == nil || err.Error() != test.errMsg) {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
+}
+
+func TestBallotUnmarshalJSON_EqualRankings(t *testing.T) {
+	var ballot *models.Ballot
+	body := `{"pollId": "poll1", "userId": "user1", "rankOrder": [[0, 2], 1, [3]]}`
+	if err := json.Unmarshal([]byte(body), &ballot); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	expected := models.NewTieredBallot("poll1", "user1", [][]int{{0, 2}, {1}, {3}})
+	if !cmp.Equal(ballot, expected, cmp.AllowUnexported(models.Ballot{})) {
+		t.Error("unexpected unmarshaled ballot:", ballot)
+	}
+	body = `{"pollId": "poll1", "userId": "user1", "rankOrder": [2, [0, 1]], "writeIns": ["kiwi"]}`
+	if err := json.Unmarshal([]byte(body), &ballot); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	expected = models.NewWriteInBallot("poll1", "user1", [][]int{{2}, {0, 1}}, []string{"kiwi"})
+	if !cmp.Equal(ballot, expected, cmp.AllowUnexported(models.Ballot{})) {
+		t.Error("unexpected unmarshaled ballot:", ballot)
+	}
+	body = `{"pollId": "poll1", "userId": "user1", "rankOrder": [0, "1"]}`
+	if err := json.Unmarshal([]byte(body), &ballot); err == nil {
+		t.Error("expected an error for a rank that is not a choice index or list")
 	}
 }
 
@@ -150,3 +240,30 @@ func TestBallotMarshalUnmarshalDynamoDBAttributeValue(t *testing.T) {
 		}
 	}
 }
+
+func TestBallotMarshalUnmarshalDynamoDBAttributeValue_EqualRankings(t *testing.T) {
+	inputBallot := models.NewWriteInBallot("poll1", "user1", [][]int{{0, 2}, {1}, {3}},
+		[]string{"kiwi"})
+	av, err := attributevalue.MarshalMap(inputBallot)
+	if err != nil {
+		t.Fatalf("failed to marshal map: %v", err)
+	}
+	// Single choices are stored as plain numbers, like ballots without equal rankings
+	rankOrder, ok := av["RankOrder"].(*types.AttributeValueMemberL)
+	if !ok || len(rankOrder.Value) != 3 {
+		t.Fatalf("unexpected rank order attribute: %#v", av["RankOrder"])
+	}
+	if _, ok := rankOrder.Value[0].(*types.AttributeValueMemberL); !ok {
+		t.Errorf("expected a list for the tied choices, got %#v", rankOrder.Value[0])
+	}
+	if _, ok := rankOrder.Value[1].(*types.AttributeValueMemberN); !ok {
+		t.Errorf("expected a number for the single choice, got %#v", rankOrder.Value[1])
+	}
+	var b *models.Ballot
+	if err = attributevalue.UnmarshalMap(av, &b); err != nil {
+		t.Errorf("failed to unmarshal map: %v", err)
+	}
+	if !cmp.Equal(inputBallot, b, cmp.AllowUnexported(models.Ballot{})) {
+		t.Errorf("unexpected unmarshaled result: %+v", b)
+	}
+}
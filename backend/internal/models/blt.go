@@ -0,0 +1,200 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxImportedBallots limits how many ballots can be imported from a single file, since a single
+// BLT line can stand for any number of identical ballots.
+const maxImportedBallots = 10000
+
+// newImportedPoll creates and validates the poll for an imported election, which elects more
+// than one choice by single transferable vote and allows partial rankings if its ballots need them.
+func newImportedPoll(prompt string, choices []string, seats int, partial bool) (*Poll, error) {
+	var opts []PollOption
+	if seats > 1 {
+		opts = append(opts, WithMethod(SingleTransferableVote), WithSeats(seats))
+	}
+	if partial {
+		opts = append(opts, WithPartialRankings(0))
+	}
+	poll := NewPoll(prompt, choices, opts...)
+	if err := poll.Validate(); err != nil {
+		return nil, err
+	}
+	return poll, nil
+}
+
+// FormatBLT writes the poll's choices and ballots in the BLT election file format read by OpenSTV
+// and other tabulators. Identical ballots are combined into a single line weighted by their
+// combined weight, choices are numbered from 1 in the poll's order, and equally ranked choices
+// are joined with "=". Write-in candidates are merged and listed after the poll's own choices.
+// The weight of each line must be a whole number, since it is read back as a number of ballots,
+// and no name can contain double quotes or line breaks, since the format cannot escape them.
+func FormatBLT(poll *Poll, ballots []*Ballot) (string, error) {
+	if poll.settings.AllowWriteIns {
+		poll, ballots, _ = mergeWriteIns(poll, ballots)
+	}
+	names := append(slices.Clone(poll.choices), poll.prompt)
+	if slices.ContainsFunc(names, func(name string) bool {
+		return strings.ContainsAny(name, "\"\r\n")
+	}) {
+		return "", errors.New("names with double quotes or line breaks cannot be exported to BLT")
+	}
+	groups := groupBallots(ballots)
+	if slices.ContainsFunc(groups, func(group ballotGroup) bool {
+		return group.count != math.Trunc(group.count)
+	}) {
+		return "", errors.New("ballots with fractional weights cannot be exported to BLT")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d %d\n", len(poll.choices), poll.Seats())
+	for _, group := range groups {
+		b.WriteString(strconv.FormatFloat(group.count, 'f', -1, 64))
+		for _, tier := range group.rankOrder {
+			for i, choiceIdx := range tier {
+				if i == 0 {
+					b.WriteByte(' ')
+				} else {
+					b.WriteByte('=')
+				}
+				b.WriteString(strconv.Itoa(choiceIdx + 1))
+			}
+		}
+		b.WriteString(" 0\n")
+	}
+	b.WriteString("0\n")
+	for _, choice := range poll.choices {
+		b.WriteString(`"` + choice + "\"\n")
+	}
+	b.WriteString(`"` + poll.prompt + "\"\n")
+	return b.String(), nil
+}
+
+// ParseBLT creates a new poll and its ballots from a file in the BLT election file format. Each
+// ballot line must have a whole number weight, which is the number of ballots it stands for.
+// Polls with more than one seat use the single transferable vote method, and polls with any
+// ballot that leaves candidates unranked allow partial rankings. Withdrawn candidates are not
+// supported.
+func ParseBLT(data string) (*Poll, []*Ballot, error) {
+	// Number the lines from 1, skipping blank lines and comments
+	type bltLine struct {
+		number int
+		text   string
+	}
+	var lines []bltLine
+	for i, text := range strings.Split(data, "\n") {
+		if text = strings.TrimSpace(text); text != "" && !strings.HasPrefix(text, "#") {
+			lines = append(lines, bltLine{i + 1, text})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil, errors.New("the BLT file is empty")
+	}
+	// The header has the numbers of candidates and seats
+	var numChoices, seats int
+	_, err := fmt.Sscanf(lines[0].text, "%d %d", &numChoices, &seats)
+	if err != nil || numChoices < 1 || seats < 1 {
+		return nil, nil, errors.New("the first line must have the numbers of candidates and seats")
+	}
+	lines = lines[1:]
+	if len(lines) > 0 && strings.HasPrefix(lines[0].text, "-") {
+		return nil, nil, errors.New("withdrawn candidates are not supported")
+	}
+	// Ballot lines continue until a line with only 0
+	type bltBallot struct {
+		line      int
+		weight    int
+		rankOrder [][]int
+	}
+	var bltBallots []bltBallot
+	partial, total := false, 0
+	for ; len(lines) > 0 && lines[0].text != "0"; lines = lines[1:] {
+		weight, rankOrder, err := parseBLTBallot(lines[0].text, numChoices)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid ballot on line %d: %w", lines[0].number, err)
+		}
+		if weight > maxImportedBallots-total {
+			return nil, nil, fmt.Errorf("a BLT file can have at most %d ballots", maxImportedBallots)
+		}
+		total += weight
+		ranked := 0
+		for _, tier := range rankOrder {
+			ranked += len(tier)
+		}
+		partial = partial || ranked < numChoices
+		bltBallots = append(bltBallots, bltBallot{lines[0].number, weight, rankOrder})
+	}
+	if len(lines) == 0 {
+		return nil, nil, errors.New("the ballots must be followed by a line with only 0")
+	}
+	// The candidates' names and the title follow the ballots, each in double quotes
+	lines = lines[1:]
+	if len(lines) != numChoices+1 {
+		return nil, nil, errors.New("there must be a name for every candidate and a title")
+	}
+	names := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line.text) < 2 || line.text[0] != '"' || line.text[len(line.text)-1] != '"' {
+			return nil, nil, fmt.Errorf("the name on line %d must be in double quotes", line.number)
+		}
+		names[i] = line.text[1 : len(line.text)-1]
+	}
+	// Create the poll and ballots
+	poll, err := newImportedPoll(names[numChoices], names[:numChoices], seats, partial)
+	if err != nil {
+		return nil, nil, err
+	}
+	ballots := make([]*Ballot, 0, total)
+	for _, bb := range bltBallots {
+		ballot := NewTieredBallot(poll.pollID, uuid.New().String(), bb.rankOrder)
+		if err := ballot.ValidateFor(poll); err != nil {
+			return nil, nil, fmt.Errorf("invalid ballot on line %d: %w", bb.line, err)
+		}
+		ballots = append(ballots, ballot)
+		// Every ballot a line stands for has the same rank order, which is never modified
+		for range bb.weight - 1 {
+			ballots = append(ballots, &Ballot{
+				pollID: poll.pollID, userID: uuid.New().String(), rankOrder: ballot.rankOrder,
+			})
+		}
+	}
+	return poll, ballots, nil
+}
+
+// parseBLTBallot parses a BLT ballot line, which has a weight followed by the ranked candidates'
+// numbers and a final 0. It returns the weight and the rank order as indices of the choices.
+func parseBLTBallot(text string, numChoices int) (int, [][]int, error) {
+	fields := strings.Fields(text)
+	// Ignore the optional ballot ID in parentheses
+	if strings.HasPrefix(fields[0], "(") {
+		fields = fields[1:]
+	}
+	if len(fields) < 2 || fields[len(fields)-1] != "0" {
+		return 0, nil, errors.New("a ballot must have a weight and end with 0")
+	}
+	weight, err := strconv.Atoi(fields[0])
+	if err != nil || weight < 1 {
+		return 0, nil, errors.New("the weight must be a positive whole number")
+	}
+	var rankOrder [][]int
+	for _, field := range fields[1 : len(fields)-1] {
+		var tier []int
+		for _, number := range strings.Split(field, "=") {
+			candidate, err := strconv.Atoi(number)
+			if err != nil || candidate < 1 || candidate > numChoices {
+				return 0, nil, errors.New("not a valid rank order")
+			}
+			tier = append(tier, candidate-1)
+		}
+		rankOrder = append(rankOrder, tier)
+	}
+	return weight, rankOrder, nil
+}
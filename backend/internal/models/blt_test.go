@@ -0,0 +1,178 @@
+package models_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+// formatBLT formats the poll and ballots as BLT, failing the test if they cannot be formatted.
+func formatBLT(t *testing.T, poll *models.Poll, ballots []*models.Ballot) string {
+	t.Helper()
+	blt, err := models.FormatBLT(poll, ballots)
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	return blt
+}
+
+func TestFormatBLT(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana", "clementine"})
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user2", []int{2, 0, 1}),
+		models.NewBallot(poll.ID(), "user3", []int{0, 1, 2}),
+		models.NewTieredBallot(poll.ID(), "user4", [][]int{{1, 2}, {0}}),
+	}
+	expected := "3 1\n" +
+		"2 1 2 3 0\n" +
+		"1 3 1 2 0\n" +
+		"1 2=3 1 0\n" +
+		"0\n" +
+		"\"apple\"\n\"banana\"\n\"clementine\"\n" +
+		"\"What is the best fruit?\"\n"
+	if blt := formatBLT(t, poll, ballots); blt != expected {
+		t.Errorf("unexpected BLT:\n%s", blt)
+	}
+}
+
+func TestFormatBLT_Weights(t *testing.T) {
+	// Whole number weights are written as numbers of ballots, but fractional ones cannot be
+	for _, test := range []struct {
+		weight   float64
+		expected string
+	}{{2, "3 1 2 0\n"}, {1.5, ""}} {
+		poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+			models.WithVoterRoll(map[string]float64{"user1": test.weight, "user2": 1}))
+		ballots := []*models.Ballot{
+			models.NewBallot(poll.ID(), "user1", []int{0, 1}),
+			models.NewBallot(poll.ID(), "user2", []int{0, 1}),
+		}
+		poll.Weigh(ballots...)
+		blt, err := models.FormatBLT(poll, ballots)
+		if test.expected == "" {
+			errMsg := "ballots with fractional weights cannot be exported to BLT"
+			if err == nil || err.Error() != errMsg {
+				t.Errorf("expected error with message %q, got %v", errMsg, err)
+			}
+		} else if err != nil || !strings.HasPrefix(blt, "2 1\n"+test.expected+"0\n") {
+			t.Errorf("unexpected BLT and error: %q, %v", blt, err)
+		}
+	}
+}
+
+func TestFormatBLT_Names(t *testing.T) {
+	tests := []struct {
+		prompt  string
+		choices []string
+		ballot  *models.Ballot
+	}{
+		{`What is the "best" fruit?`, []string{"apple", "banana"}, nil},
+		{"What is the best\nfruit?", []string{"apple", "banana"}, nil},
+		{"What is the best fruit?", []string{"apple", "banana\r"}, nil},
+		{
+			"What is the best fruit?", []string{"apple", "banana"},
+			models.NewWriteInBallot("", "user2", [][]int{{2}}, []string{`"kiwi"`}),
+		},
+	}
+	errMsg := "names with double quotes or line breaks cannot be exported to BLT"
+	for _, test := range tests {
+		poll := models.NewPoll(test.prompt, test.choices, models.WithWriteIns())
+		ballots := []*models.Ballot{models.NewBallot(poll.ID(), "user1", []int{0, 1})}
+		if test.ballot != nil {
+			ballots = append(ballots, test.ballot)
+		}
+		if _, err := models.FormatBLT(poll, ballots); err == nil || err.Error() != errMsg {
+			t.Errorf("expected error with message %q, got %v", errMsg, err)
+		}
+	}
+}
+
+func TestParseBLT(t *testing.T) {
+	blt := `4 2
+# A comment before the ballots
+3 1 2 0
+(b2) 2 3 4 1=2 0
+1 4 0
+
+0
+"apple"
+"banana"
+"clementine"
+"durian"
+"Which two fruits should we stock?"
+`
+	poll, ballots, err := models.ParseBLT(blt)
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	if err := poll.Validate(); err != nil {
+		t.Error("expected a valid poll, got:", err)
+	}
+	if poll.Method() != models.SingleTransferableVote || poll.Seats() != 2 || poll.MinRanks() != 1 {
+		t.Error("unexpected poll:", poll)
+	}
+	if len(ballots) != 6 {
+		t.Fatal("unexpected number of ballots:", len(ballots))
+	}
+	// Every ballot belongs to the new poll, and each has its own voter
+	userIDs := make(map[string]bool)
+	for _, ballot := range ballots {
+		if ballot.PollID() != poll.ID() {
+			t.Error("unexpected poll ID:", ballot.PollID())
+		}
+		// The string form of a ballot includes its user ID
+		userIDs[ballot.String()] = true
+	}
+	if len(userIDs) != len(ballots) {
+		t.Error("expected every ballot to have a different user ID")
+	}
+	// Formatting the parsed poll gives back the same ballots
+	expected := "4 2\n3 1 2 0\n2 3 4 1=2 0\n1 4 0\n0\n" +
+		"\"apple\"\n\"banana\"\n\"clementine\"\n\"durian\"\n" +
+		"\"Which two fruits should we stock?\"\n"
+	if formatted := formatBLT(t, poll, ballots); formatted != expected {
+		t.Errorf("unexpected BLT:\n%s", formatted)
+	}
+}
+
+func TestParseBLT_Error(t *testing.T) {
+	tests := []struct {
+		errMsg, blt string
+	}{
+		{"the BLT file is empty", "\n\n"},
+		{"the first line must have the numbers of candidates and seats", "two 1\n"},
+		{"withdrawn candidates are not supported", "3 1\n-2\n1 1 2 3 0\n0\n"},
+		{
+			"invalid ballot on line 2: a ballot must have a weight and end with 0",
+			"2 1\n1 1 2\n0\n\"a\"\n\"b\"\n\"t\"\n",
+		},
+		{
+			"invalid ballot on line 2: the weight must be a positive whole number",
+			"2 1\n1.5 1 2 0\n0\n\"a\"\n\"b\"\n\"t\"\n",
+		},
+		{
+			"invalid ballot on line 2: not a valid rank order",
+			"2 1\n1 1 3 0\n0\n\"a\"\n\"b\"\n\"t\"\n",
+		},
+		{
+			"invalid ballot on line 2: not a valid rank order",
+			"2 1\n1 1 1 0\n0\n\"a\"\n\"b\"\n\"t\"\n",
+		},
+		{
+			"invalid ballot on line 3: there must be at least one ranking",
+			"2 1\n1 1 2 0\n1 0\n0\n\"a\"\n\"b\"\n\"t\"\n",
+		},
+		{"a BLT file can have at most 10000 ballots", "2 1\n10001 1 2 0\n0\n"},
+		{"the ballots must be followed by a line with only 0", "2 1\n1 1 2 0\n"},
+		{"there must be a name for every candidate and a title", "2 1\n1 1 2 0\n0\n\"a\"\n"},
+		{"the name on line 5 must be in double quotes", "2 1\n1 1 2 0\n0\n\"a\"\nb\n\"t\"\n"},
+		{"choices must be unique", "2 1\n1 1 2 0\n0\n\"a\"\n\"a\"\n\"t\"\n"},
+	}
+	for _, test := range tests {
+		if _, _, err := models.ParseBLT(test.blt); err == nil || err.Error() != test.errMsg {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The IDs of the objects that appear once in a cast vote record report exported from a poll
+const (
+	cvrDeviceID   = "verdict"
+	cvrElectionID = "election"
+	cvrContestID  = "contest"
+	cvrGpUnitID   = "gpunit"
+	cvrSnapshotID = "snapshot"
+)
+
+// cvrReport is the subset of the NIST SP 1500-103 cast vote record common data format that
+// describes a single ranked choice contest and its ballots.
+type cvrReport struct {
+	Type                      string        `json:"@type"`
+	Version                   string        `json:"Version"`
+	GeneratedDate             string        `json:"GeneratedDate"`
+	ReportGeneratingDeviceIds []string      `json:"ReportGeneratingDeviceIds"`
+	ReportingDevice           []cvrObject   `json:"ReportingDevice"`
+	GpUnit                    []cvrGpUnit   `json:"GpUnit"`
+	Election                  []cvrElection `json:"Election"`
+	CVR                       []cvrRecord   `json:"CVR"`
+}
+
+// cvrObject is an object in a cast vote record report that only needs its type and ID.
+type cvrObject struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id"`
+}
+
+type cvrGpUnit struct {
+	Type     string `json:"@type"`
+	ID       string `json:"@id"`
+	UnitType string `json:"Type"`
+}
+
+type cvrElection struct {
+	Type            string         `json:"@type"`
+	ID              string         `json:"@id"`
+	Name            string         `json:"Name,omitempty"`
+	ElectionScopeID string         `json:"ElectionScopeId"`
+	Candidate       []cvrCandidate `json:"Candidate"`
+	Contest         []cvrContest   `json:"Contest"`
+}
+
+type cvrCandidate struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id"`
+	Name string `json:"Name"`
+}
+
+type cvrContest struct {
+	Type             string                `json:"@type"`
+	ID               string                `json:"@id"`
+	Name             string                `json:"Name,omitempty"`
+	NumberElected    int                   `json:"NumberElected,omitempty"`
+	ContestSelection []cvrContestSelection `json:"ContestSelection"`
+}
+
+type cvrContestSelection struct {
+	Type         string   `json:"@type"`
+	ID           string   `json:"@id"`
+	CandidateIDs []string `json:"CandidateIds"`
+	IsWriteIn    bool     `json:"IsWriteIn,omitempty"`
+}
+
+type cvrRecord struct {
+	Type              string        `json:"@type"`
+	UniqueID          string        `json:"UniqueId,omitempty"`
+	ElectionID        string        `json:"ElectionId"`
+	CurrentSnapshotID string        `json:"CurrentSnapshotId"`
+	CVRSnapshot       []cvrSnapshot `json:"CVRSnapshot"`
+}
+
+type cvrSnapshot struct {
+	Type         string           `json:"@type"`
+	ID           string           `json:"@id"`
+	SnapshotType string           `json:"Type"`
+	CVRContest   []cvrVoteContest `json:"CVRContest"`
+}
+
+// cvrVoteContest is a CVRContest, which records a voter's selections in a contest.
+type cvrVoteContest struct {
+	Type                string                    `json:"@type"`
+	ContestID           string                    `json:"ContestId"`
+	CVRContestSelection []cvrVoteContestSelection `json:"CVRContestSelection"`
+}
+
+// cvrVoteContestSelection is a CVRContestSelection, which records the ranks a voter gave one
+// contest selection.
+type cvrVoteContestSelection struct {
+	Type               string                 `json:"@type"`
+	ContestSelectionID string                 `json:"ContestSelectionId"`
+	Rank               int                    `json:"Rank,omitempty"`
+	SelectionPosition  []cvrSelectionPosition `json:"SelectionPosition"`
+}
+
+type cvrSelectionPosition struct {
+	Type          string `json:"@type"`
+	HasIndication string `json:"HasIndication"`
+	IsAllocable   string `json:"IsAllocable,omitempty"`
+	NumberVotes   int    `json:"NumberVotes"`
+	Rank          int    `json:"Rank,omitempty"`
+}
+
+// cvrCandidateID and cvrSelectionID identify the candidate and contest selection for a choice.
+func cvrCandidateID(choiceIdx int) string { return "candidate-" + strconv.Itoa(choiceIdx) }
+func cvrSelectionID(choiceIdx int) string { return "selection-" + strconv.Itoa(choiceIdx) }
+
+// FormatCVR writes the poll's choices and ballots as a cast vote record report in the NIST
+// SP 1500-103 JSON common data format read by RCTab and other tabulators. Each ballot is a
+// record numbered in order, with a rank for each ranked choice. Equally ranked choices share a
+// rank, and write-in candidates are merged and listed after the poll's own choices. Ballot
+// weights are not part of the format, so a ballot with a whole number weight is written as that
+// many records, up to the number of ballots that can be imported, and ballots with fractional
+// weights cannot be written at all.
+func FormatCVR(poll *Poll, ballots []*Ballot, generated time.Time) ([]byte, error) {
+	// Sum the weights as floats, which cannot overflow like the number of records could
+	totalWeight := 0.0
+	for _, ballot := range ballots {
+		if ballot.Weight() != math.Trunc(ballot.Weight()) {
+			return nil, errors.New("ballots with fractional weights cannot be exported to CVR")
+		}
+		totalWeight += ballot.value()
+	}
+	// Without a voter roll, there is already a stored ballot for every record
+	if len(poll.voterRoll) > 0 && totalWeight > maxImportedBallots {
+		return nil, fmt.Errorf("weighted ballots can be exported to CVR as at most %d records",
+			maxImportedBallots)
+	}
+	numListed := len(poll.choices)
+	if poll.settings.AllowWriteIns {
+		poll, ballots, _ = mergeWriteIns(poll, ballots)
+	}
+	election := cvrElection{
+		Type:            "CVR.Election",
+		ID:              cvrElectionID,
+		Name:            poll.prompt,
+		ElectionScopeID: cvrGpUnitID,
+		Contest: []cvrContest{{
+			Type:          "CVR.CandidateContest",
+			ID:            cvrContestID,
+			Name:          poll.prompt,
+			NumberElected: poll.Seats(),
+		}},
+	}
+	for i, choice := range poll.choices {
+		election.Candidate = append(election.Candidate, cvrCandidate{
+			Type: "CVR.Candidate", ID: cvrCandidateID(i), Name: choice,
+		})
+		election.Contest[0].ContestSelection = append(election.Contest[0].ContestSelection,
+			cvrContestSelection{
+				Type:         "CVR.CandidateSelection",
+				ID:           cvrSelectionID(i),
+				CandidateIDs: []string{cvrCandidateID(i)},
+				IsWriteIn:    i >= numListed,
+			})
+	}
+	records := make([]cvrRecord, 0, len(ballots))
+	for _, ballot := range ballots {
+		contest := cvrVoteContest{Type: "CVR.CVRContest", ContestID: cvrContestID}
+		for rank, tier := range ballot.rankOrder {
+			for _, choiceIdx := range tier {
+				contest.CVRContestSelection = append(contest.CVRContestSelection,
+					cvrVoteContestSelection{
+						Type:               "CVR.CVRContestSelection",
+						ContestSelectionID: cvrSelectionID(choiceIdx),
+						Rank:               rank + 1,
+						SelectionPosition: []cvrSelectionPosition{{
+							Type:          "CVR.SelectionPosition",
+							HasIndication: "yes",
+							IsAllocable:   "yes",
+							NumberVotes:   1,
+							Rank:          rank + 1,
+						}},
+					})
+			}
+		}
+		record := cvrRecord{
+			Type:              "CVR.CVR",
+			ElectionID:        cvrElectionID,
+			CurrentSnapshotID: cvrSnapshotID,
+			CVRSnapshot: []cvrSnapshot{{
+				Type:         "CVR.CVRSnapshot",
+				ID:           cvrSnapshotID,
+				SnapshotType: "original",
+				CVRContest:   []cvrVoteContest{contest},
+			}},
+		}
+		// A ballot standing for several identical ballots, or weighted as several votes, is
+		// written as a record for each vote
+		for range int(ballot.value()) {
+			record.UniqueID = strconv.Itoa(len(records) + 1)
+			records = append(records, record)
+		}
+	}
+	// The poll's voters are the only geopolitical unit
+	gpUnit := cvrGpUnit{Type: "CVR.GpUnit", ID: cvrGpUnitID, UnitType: "other"}
+	return json.Marshal(&cvrReport{
+		Type:                      "CVR.CastVoteRecordReport",
+		Version:                   "1.0.0",
+		GeneratedDate:             generated.UTC().Format(time.RFC3339),
+		ReportGeneratingDeviceIds: []string{cvrDeviceID},
+		ReportingDevice:           []cvrObject{{Type: "CVR.ReportingDevice", ID: cvrDeviceID}},
+		GpUnit:                    []cvrGpUnit{gpUnit},
+		Election:                  []cvrElection{election},
+		CVR:                       records,
+	})
+}
+
+// ParseCVR creates a new poll and its ballots from a cast vote record report in the NIST
+// SP 1500-103 JSON common data format. The report must have a single election with a single
+// contest, whose contest selections become the poll's choices in order. Each record's current
+// snapshot is read, taking each selection's rank from its indicated and allocable selection
+// positions, or from the selection itself if its positions have no rank. A selection ranked more
+// than once keeps its highest rank, and selections sharing a rank are ranked equally. Records
+// that rank nothing are skipped. Polls with more than one seat use the single transferable vote
+// method, and polls with any ballot that leaves choices unranked allow partial rankings.
+func ParseCVR(data []byte) (*Poll, []*Ballot, error) {
+	var report cvrReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, nil, errors.New("invalid JSON")
+	}
+	if len(report.Election) != 1 || len(report.Election[0].Contest) != 1 {
+		return nil, nil, errors.New("the report must have a single election with a single contest")
+	}
+	election, contest := report.Election[0], report.Election[0].Contest[0]
+	// The contest selections become the poll's choices
+	candidateNames := make(map[string]string, len(election.Candidate))
+	for _, candidate := range election.Candidate {
+		candidateNames[candidate.ID] = candidate.Name
+	}
+	choices := make([]string, len(contest.ContestSelection))
+	selectionIndices := make(map[string]int, len(contest.ContestSelection))
+	for i, selection := range contest.ContestSelection {
+		if len(selection.CandidateIDs) != 1 || candidateNames[selection.CandidateIDs[0]] == "" {
+			return nil, nil, errors.New("every contest selection must be a single named candidate")
+		}
+		choices[i] = candidateNames[selection.CandidateIDs[0]]
+		selectionIndices[selection.ID] = i
+	}
+	prompt := cmp.Or(contest.Name, election.Name)
+	// Read the rank order from each record
+	var rankOrders [][][]int
+	partial := false
+	for i, record := range report.CVR {
+		ranks, err := record.ranks(contest.ID, selectionIndices)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid record at index %d: %w", i, err)
+		}
+		if len(ranks) == 0 {
+			continue
+		}
+		if len(rankOrders) == maxImportedBallots {
+			return nil, nil,
+				fmt.Errorf("a cast vote record report can have at most %d ballots", maxImportedBallots)
+		}
+		partial = partial || len(ranks) < len(choices)
+		rankOrders = append(rankOrders, tiersByRank(ranks))
+	}
+	// Create the poll and ballots
+	poll, err := newImportedPoll(prompt, choices, contest.NumberElected, partial)
+	if err != nil {
+		return nil, nil, err
+	}
+	ballots := make([]*Ballot, len(rankOrders))
+	for i, rankOrder := range rankOrders {
+		ballots[i] = NewTieredBallot(poll.pollID, uuid.New().String(), rankOrder)
+		if err := ballots[i].ValidateFor(poll); err != nil {
+			return nil, nil, fmt.Errorf("invalid ballot %d: %w", i+1, err)
+		}
+	}
+	return poll, ballots, nil
+}
+
+// ranks reads the rank of each choice the record's current snapshot ranks in the contest, keyed
+// by choice index.
+func (r *cvrRecord) ranks(contestID string, selectionIndices map[string]int) (map[int]int, error) {
+	snapshotIdx := slices.IndexFunc(r.CVRSnapshot, func(s cvrSnapshot) bool {
+		return s.ID == r.CurrentSnapshotID
+	})
+	if snapshotIdx < 0 {
+		return nil, errors.New("the current snapshot is missing")
+	}
+	ranks := make(map[int]int)
+	for _, contest := range r.CVRSnapshot[snapshotIdx].CVRContest {
+		if contest.ContestID != contestID {
+			continue
+		}
+		for _, selection := range contest.CVRContestSelection {
+			choiceIdx, ok := selectionIndices[selection.ContestSelectionID]
+			if !ok {
+				return nil, errors.New("unknown contest selection")
+			}
+			for _, rank := range selection.indicatedRanks() {
+				if rank < 1 {
+					return nil, errors.New("ranks must be positive")
+				}
+				if current, ok := ranks[choiceIdx]; !ok || rank < current {
+					ranks[choiceIdx] = rank
+				}
+			}
+		}
+	}
+	return ranks, nil
+}
+
+// indicatedRanks lists the ranks the voter marked for the selection, leaving out positions that
+// were not indicated or cannot be allocated. Positions without a rank of their own take the
+// selection's rank.
+func (s *cvrVoteContestSelection) indicatedRanks() []int {
+	if len(s.SelectionPosition) == 0 {
+		return []int{s.Rank}
+	}
+	var ranks []int
+	for _, position := range s.SelectionPosition {
+		if position.HasIndication == "no" || position.IsAllocable == "no" {
+			continue
+		}
+		ranks = append(ranks, cmp.Or(position.Rank, s.Rank))
+	}
+	return ranks
+}
+
+// tiersByRank groups the choices by rank, from the highest rank to the lowest, with the choices
+// in each tier in index order. Gaps between ranks are closed.
+func tiersByRank(ranks map[int]int) [][]int {
+	byRank := make(map[int][]int)
+	for choiceIdx, rank := range ranks {
+		byRank[rank] = append(byRank[rank], choiceIdx)
+	}
+	var tiers [][]int
+	for _, rank := range slices.Sorted(maps.Keys(byRank)) {
+		tiers = append(tiers, slices.Sorted(slices.Values(byRank[rank])))
+	}
+	return tiers
+}
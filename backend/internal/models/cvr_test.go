@@ -0,0 +1,217 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func TestFormatCVR(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana", "clementine"},
+		models.WithPartialRankings(1), models.WithWriteIns())
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1, 2}),
+		models.NewTieredBallot(poll.ID(), "user2", [][]int{{1, 2}, {0}}),
+		models.NewBallot(poll.ID(), "user3", []int{2}),
+		models.NewWriteInBallot(poll.ID(), "user4", [][]int{{3}, {0}}, []string{"kiwi"}),
+	}
+	generated := time.Date(2025, time.March, 4, 5, 6, 7, 0, time.UTC)
+	body, err := models.FormatCVR(poll, ballots, generated)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var report struct {
+		Type          string `json:"@type"`
+		GeneratedDate string
+		Election      []struct {
+			Contest []struct {
+				ContestSelection []struct {
+					IsWriteIn bool
+				}
+			}
+		}
+		CVR []struct {
+			UniqueID string `json:"UniqueId"`
+		}
+	}
+	if err := json.Unmarshal(body, &report); err != nil {
+		t.Fatal(err.Error())
+	}
+	if report.Type != "CVR.CastVoteRecordReport" ||
+		report.GeneratedDate != "2025-03-04T05:06:07Z" ||
+		len(report.CVR) != 4 || report.CVR[3].UniqueID != "4" {
+		t.Errorf("unexpected report: %s", body)
+	}
+	selections := report.Election[0].Contest[0].ContestSelection
+	if len(selections) != 4 || selections[2].IsWriteIn || !selections[3].IsWriteIn {
+		t.Errorf("unexpected contest selections: %s", body)
+	}
+	// Importing the report gives back the same poll and ballots, with the write-in as a choice
+	parsedPoll, parsedBallots, err := models.ParseCVR(body)
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	merged := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine", "kiwi"}, models.WithPartialRankings(1))
+	mergedBallots := []*models.Ballot{
+		models.NewBallot(merged.ID(), "user1", []int{0, 1, 2}),
+		models.NewTieredBallot(merged.ID(), "user2", [][]int{{1, 2}, {0}}),
+		models.NewBallot(merged.ID(), "user3", []int{2}),
+		models.NewBallot(merged.ID(), "user4", []int{3, 0}),
+	}
+	if formatBLT(t, parsedPoll, parsedBallots) != formatBLT(t, merged, mergedBallots) {
+		t.Errorf("unexpected parsed poll and ballots:\n%s",
+			formatBLT(t, parsedPoll, parsedBallots))
+	}
+}
+
+func TestFormatCVR_Weights(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithVoterRoll(map[string]float64{"user1": 3, "user2": 1, "user3": 1}))
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0}),
+		models.NewBallot(poll.ID(), "user3", []int{1, 0}),
+	}
+	poll.Weigh(ballots...)
+	// Whole number weights are written as a record for each vote, even from a tally
+	recreated, err := models.TallyOf(poll, ballots).Ballots()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := models.FormatCVR(poll, recreated, time.Now())
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	_, parsedBallots, err := models.ParseCVR(body)
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	if len(parsedBallots) != 5 {
+		t.Error("unexpected number of records:", len(parsedBallots))
+	}
+	// Fractional weights cannot be written as records
+	poll = models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithVoterRoll(map[string]float64{"user1": 1.5}))
+	ballot := models.NewBallot(poll.ID(), "user1", []int{0, 1})
+	poll.Weigh(ballot)
+	errMsg := "ballots with fractional weights cannot be exported to CVR"
+	_, err = models.FormatCVR(poll, []*models.Ballot{ballot}, time.Now())
+	if err == nil || err.Error() != errMsg {
+		t.Errorf("expected error with message %q, got %v", errMsg, err)
+	}
+	// Weights too heavy to write as a record for each vote are refused instead of lost
+	for _, weight := range []float64{10001, 1e19} {
+		poll = models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+			models.WithVoterRoll(map[string]float64{"user1": weight}))
+		ballot = models.NewBallot(poll.ID(), "user1", []int{0, 1})
+		poll.Weigh(ballot)
+		errMsg = "weighted ballots can be exported to CVR as at most 10000 records"
+		_, err = models.FormatCVR(poll, []*models.Ballot{ballot}, time.Now())
+		if err == nil || err.Error() != errMsg {
+			t.Errorf("expected error with message %q, got %v", errMsg, err)
+		}
+	}
+}
+
+func TestParseCVR(t *testing.T) {
+	// Ranks on selection positions as written by other tabulators, with a selection ranked
+	// twice, an overvote, a position that cannot be allocated, and a blank record
+	report := `{
+		"@type": "CVR.CastVoteRecordReport",
+		"Election": [{
+			"@id": "e1",
+			"Candidate": [
+				{"@id": "c1", "Name": "Alice"},
+				{"@id": "c2", "Name": "Bob"},
+				{"@id": "c3", "Name": "Carol"}
+			],
+			"Contest": [{
+				"@id": "mayor",
+				"Name": "Mayor",
+				"ContestSelection": [
+					{"@id": "s1", "CandidateIds": ["c1"]},
+					{"@id": "s2", "CandidateIds": ["c2"]},
+					{"@id": "s3", "CandidateIds": ["c3"]}
+				]
+			}]
+		}],
+		"CVR": [
+			{"CurrentSnapshotId": "a", "CVRSnapshot": [
+				{"@id": "old", "CVRContest": []},
+				{"@id": "a", "CVRContest": [{"ContestId": "mayor", "CVRContestSelection": [
+					{"ContestSelectionId": "s2", "SelectionPosition": [
+						{"HasIndication": "yes", "Rank": 3},
+						{"HasIndication": "yes", "Rank": 1}
+					]},
+					{"ContestSelectionId": "s1", "SelectionPosition": [
+						{"HasIndication": "yes", "Rank": 2}
+					]}
+				]}]}
+			]},
+			{"CurrentSnapshotId": "b", "CVRSnapshot": [
+				{"@id": "b", "CVRContest": [{"ContestId": "mayor", "CVRContestSelection": [
+					{"ContestSelectionId": "s1", "SelectionPosition": [
+						{"HasIndication": "yes", "Rank": 1}
+					]},
+					{"ContestSelectionId": "s3", "SelectionPosition": [
+						{"HasIndication": "yes", "Rank": 1}
+					]},
+					{"ContestSelectionId": "s2", "SelectionPosition": [
+						{"HasIndication": "yes", "IsAllocable": "no", "Rank": 2}
+					]}
+				]}]}
+			]},
+			{"CurrentSnapshotId": "c", "CVRSnapshot": [{"@id": "c", "CVRContest": []}]}
+		]
+	}`
+	poll, ballots, err := models.ParseCVR([]byte(report))
+	if err != nil {
+		t.Fatal("expected success, got:", err)
+	}
+	expected := "3 1\n1 2 1 0\n1 1=3 0\n0\n\"Alice\"\n\"Bob\"\n\"Carol\"\n\"Mayor\"\n"
+	if blt := formatBLT(t, poll, ballots); blt != expected {
+		t.Errorf("unexpected poll and ballots:\n%s", blt)
+	}
+}
+
+func TestParseCVR_Error(t *testing.T) {
+	election := `"Election": [{"Candidate": [{"@id": "c1", "Name": "yes"},
+		{"@id": "c2", "Name": "no"}], "Contest": [{"@id": "k", "Name": "Adopt?",
+		"ContestSelection": [{"@id": "s1", "CandidateIds": ["c1"]},
+		{"@id": "s2", "CandidateIds": ["c2"]}]}]}]`
+	tests := []struct {
+		errMsg, report string
+	}{
+		{"invalid JSON", `{"Election": 3}`},
+		{"the report must have a single election with a single contest", `{"Election": []}`},
+		{
+			"every contest selection must be a single named candidate",
+			`{"Election": [{"Contest": [{"ContestSelection": [{"CandidateIds": ["x"]}]}]}]}`,
+		},
+		{
+			"invalid record at index 0: the current snapshot is missing",
+			`{` + election + `, "CVR": [{"CurrentSnapshotId": "a"}]}`,
+		},
+		{
+			"invalid record at index 0: unknown contest selection",
+			`{` + election + `, "CVR": [{"CurrentSnapshotId": "a", "CVRSnapshot": [{"@id": "a",
+			"CVRContest": [{"ContestId": "k", "CVRContestSelection": [
+			{"ContestSelectionId": "s3", "Rank": 1}]}]}]}]}`,
+		},
+		{
+			"invalid record at index 0: ranks must be positive",
+			`{` + election + `, "CVR": [{"CurrentSnapshotId": "a", "CVRSnapshot": [{"@id": "a",
+			"CVRContest": [{"ContestId": "k", "CVRContestSelection": [
+			{"ContestSelectionId": "s1"}]}]}]}]}`,
+		},
+	}
+	for _, test := range tests {
+		_, _, err := models.ParseCVR([]byte(test.report))
+		if err == nil || err.Error() != test.errMsg {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// pairwisePreferences totals, for each ordered pair of choices, the weight of the ballots that
+// rank the first choice above the second. The total for choices i over j is at [i][j].
+func pairwisePreferences(numChoices int, ballots []*Ballot) [][]float64 {
+	preferences := make([][]float64, numChoices)
+	for i := range preferences {
+		preferences[i] = make([]float64, numChoices)
+	}
+	for _, ballot := range ballots {
+		// Choices ranked earlier on the ballot are preferred to choices ranked later, and every
+		// ranked choice is preferred to every unranked choice. Choices in the same tier, including
+		// the unranked choices, are preferred to each other by neither side.
+		tierOf, weight := ballot.tierIndices(numChoices), ballot.value()
+		for higher := range numChoices {
+			for lower := range numChoices {
+				if tierOf[higher] < tierOf[lower] {
+					preferences[higher][lower] += weight
+				}
+			}
+		}
+	}
+	return preferences
+}
+
+// rankByBeats orders the choices by a relation in which beats(i, j) reports whether choice i
+// finishes ahead of choice j. The relation only needs to be a strict partial order, so each place
+// goes to the remaining choices that no other remaining choice beats, and choices in the same
+// place are tied.
+func rankByBeats(numChoices int, beats func(i, j int) bool) [][]int {
+	remaining := make([]int, numChoices)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	var ranking [][]int
+	for len(remaining) > 0 {
+		var unbeaten, rest []int
+		for _, candidate := range remaining {
+			if slices.ContainsFunc(remaining, func(other int) bool {
+				return beats(other, candidate)
+			}) {
+				rest = append(rest, candidate)
+			} else {
+				unbeaten = append(unbeaten, candidate)
+			}
+		}
+		ranking, remaining = append(ranking, unbeaten), rest
+	}
+	return ranking
+}
+
+type pairwiseResult struct {
+	poll    *Poll
+	ballots []*Ballot
+	// The weight of the ballots ranking choice i above choice j, at [i][j]
+	preferences [][]float64
+	// The indices of the Condorcet winner and loser, or -1 if there is none
+	condorcetWinnerIdx, condorcetLoserIdx int
+	smithSet                              []int
+}
+
+// NewPairwiseResult computes the head-to-head matrix for the provided poll and ballots, along
+// with the Condorcet winner, the Condorcet loser, and the Smith set. Write-in candidates are
+// included after the poll's own choices.
+func NewPairwiseResult(poll *Poll, ballots []*Ballot) (*pairwiseResult, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	if poll.settings.AllowWriteIns {
+		poll, ballots, _ = mergeWriteIns(poll, ballots)
+	}
+	res := &pairwiseResult{
+		poll:               poll,
+		ballots:            ballots,
+		preferences:        pairwisePreferences(len(poll.choices), ballots),
+		condorcetWinnerIdx: -1,
+		condorcetLoserIdx:  -1,
+	}
+	res.findCondorcetWinnerAndLoser()
+	res.findSmithSet()
+	return res, nil
+}
+
+// beats reports whether more ballots rank choice i above choice j than the reverse.
+func (r *pairwiseResult) beats(i, j int) bool { return r.preferences[i][j] > r.preferences[j][i] }
+
+// findCondorcetWinnerAndLoser looks for a choice that beats every other choice head-to-head and
+// a choice that loses to every other choice head-to-head.
+func (r *pairwiseResult) findCondorcetWinnerAndLoser() {
+	n := len(r.poll.choices)
+	for i := range n {
+		winsAll, losesAll := true, true
+		for j := range n {
+			if i != j {
+				winsAll = winsAll && r.beats(i, j)
+				losesAll = losesAll && r.beats(j, i)
+			}
+		}
+		if winsAll {
+			r.condorcetWinnerIdx = i
+		}
+		if losesAll {
+			r.condorcetLoserIdx = i
+		}
+	}
+}
+
+// findSmithSet finds the smallest set of choices that each beat every choice outside the set.
+// A choice is in the Smith set exactly when it can reach every other choice through a chain of
+// head-to-head contests that it does not lose.
+func (r *pairwiseResult) findSmithSet() {
+	n := len(r.poll.choices)
+	reaches := make([][]bool, n)
+	for i := range n {
+		reaches[i] = make([]bool, n)
+		for j := range n {
+			reaches[i][j] = i == j || !r.beats(j, i)
+		}
+	}
+	// Transitive closure
+	for k := range n {
+		for i := range n {
+			for j := range n {
+				reaches[i][j] = reaches[i][j] || (reaches[i][k] && reaches[k][j])
+			}
+		}
+	}
+	for i := range n {
+		if !slices.Contains(reaches[i], false) {
+			r.smithSet = append(r.smithSet, i)
+		}
+	}
+}
+
+// MarshalJSON is a custom marshaler that formats relevant data from the computed result. The
+// rows and columns of the matrix follow the order of the poll's choices.
+func (r *pairwiseResult) MarshalJSON() ([]byte, error) {
+	var condorcetWinner, condorcetLoser *string
+	if r.condorcetWinnerIdx >= 0 {
+		condorcetWinner = &r.poll.choices[r.condorcetWinnerIdx]
+	}
+	if r.condorcetLoserIdx >= 0 {
+		condorcetLoser = &r.poll.choices[r.condorcetLoserIdx]
+	}
+	return json.Marshal(&struct {
+		Prompt          string      `json:"prompt"`
+		TotalVotes      float64     `json:"totalVotes"`
+		Choices         []string    `json:"choices"`
+		Preferences     [][]float64 `json:"preferences"`
+		CondorcetWinner *string     `json:"condorcetWinner"`
+		CondorcetLoser  *string     `json:"condorcetLoser"`
+		SmithSet        []string    `json:"smithSet"`
+	}{
+		Prompt:          r.poll.prompt,
+		TotalVotes:      totalVotes(r.ballots),
+		Choices:         r.poll.choices,
+		Preferences:     r.preferences,
+		CondorcetWinner: condorcetWinner,
+		CondorcetLoser:  condorcetLoser,
+		SmithSet:        r.poll.choiceNames(r.smithSet),
+	})
+}
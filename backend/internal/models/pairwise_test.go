@@ -0,0 +1,149 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+type pairwiseJSON struct {
+	TotalVotes      int      `json:"totalVotes"`
+	Choices         []string `json:"choices"`
+	Preferences     [][]int  `json:"preferences"`
+	CondorcetWinner *string  `json:"condorcetWinner"`
+	CondorcetLoser  *string  `json:"condorcetLoser"`
+	SmithSet        []string `json:"smithSet"`
+}
+
+func parsePairwise(t *testing.T, poll *models.Poll, rankOrders map[int][]int) pairwiseJSON {
+	ballotWithRanks := ballotClosure(poll.ID())
+	var ballots []*models.Ballot
+	for count, rankOrder := range rankOrders {
+		for range count {
+			ballots = append(ballots, ballotWithRanks(rankOrder))
+		}
+	}
+	result, err := models.NewPairwiseResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed pairwiseJSON
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	return parsed
+}
+
+func TestPairwise_CondorcetLoserOnly(t *testing.T) {
+	poll, _ := threeOptionPoll()
+	// Keyed by the number of ballots with each rank order
+	parsed := parsePairwise(t, poll, map[int][]int{
+		3: {0, 1, 2},
+		2: {1, 0, 2},
+		1: {2, 1, 0},
+	})
+	if parsed.TotalVotes != 6 {
+		t.Error("unexpected total votes:", parsed.TotalVotes)
+	}
+	if !cmp.Equal(parsed.Preferences, [][]int{{0, 3, 5}, {3, 0, 5}, {1, 1, 0}}) {
+		t.Error("unexpected preferences:", parsed.Preferences)
+	}
+	// apple and banana tie head-to-head, so neither is a Condorcet winner
+	if parsed.CondorcetWinner != nil {
+		t.Error("unexpected Condorcet winner:", *parsed.CondorcetWinner)
+	}
+	if parsed.CondorcetLoser == nil || *parsed.CondorcetLoser != "clementine" {
+		t.Error("unexpected Condorcet loser:", parsed.CondorcetLoser)
+	}
+	if !cmp.Equal(parsed.SmithSet, []string{"apple", "banana"}) {
+		t.Error("unexpected Smith set:", parsed.SmithSet)
+	}
+}
+
+func TestPairwise_CondorcetWinnerAndLoser(t *testing.T) {
+	poll, _ := fourOptionPoll()
+	parsed := parsePairwise(t, poll, map[int][]int{
+		4: {2, 0, 1, 3},
+		3: {1, 2, 0, 3},
+		2: {0, 2, 3, 1},
+	})
+	if parsed.CondorcetWinner == nil || *parsed.CondorcetWinner != "clementine" {
+		t.Error("unexpected Condorcet winner:", parsed.CondorcetWinner)
+	}
+	if parsed.CondorcetLoser == nil || *parsed.CondorcetLoser != "durian" {
+		t.Error("unexpected Condorcet loser:", parsed.CondorcetLoser)
+	}
+	if !cmp.Equal(parsed.SmithSet, []string{"clementine"}) {
+		t.Error("unexpected Smith set:", parsed.SmithSet)
+	}
+}
+
+func TestPairwise_CycleSmithSet(t *testing.T) {
+	poll, _ := fourOptionPoll()
+	// apple beats banana, banana beats clementine, and clementine beats apple, and all three
+	// beat durian
+	parsed := parsePairwise(t, poll, map[int][]int{
+		4: {0, 1, 2, 3},
+		3: {1, 2, 0, 3},
+		2: {2, 0, 1, 3},
+	})
+	if parsed.CondorcetWinner != nil {
+		t.Error("unexpected Condorcet winner:", *parsed.CondorcetWinner)
+	}
+	if parsed.CondorcetLoser == nil || *parsed.CondorcetLoser != "durian" {
+		t.Error("unexpected Condorcet loser:", parsed.CondorcetLoser)
+	}
+	if !cmp.Equal(parsed.SmithSet, []string{"apple", "banana", "clementine"}) {
+		t.Error("unexpected Smith set:", parsed.SmithSet)
+	}
+}
+
+func TestPairwise_PartialRankings(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine"}, models.WithPartialRankings(1))
+	// Ranked choices are preferred to unranked ones, which are preferred to each other by no one
+	parsed := parsePairwise(t, poll, map[int][]int{
+		2: {0},
+		1: {1, 2},
+	})
+	if !cmp.Equal(parsed.Preferences, [][]int{{0, 2, 2}, {1, 0, 1}, {1, 0, 0}}) {
+		t.Error("unexpected preferences:", parsed.Preferences)
+	}
+	if parsed.CondorcetWinner == nil || *parsed.CondorcetWinner != "apple" {
+		t.Error("unexpected Condorcet winner:", parsed.CondorcetWinner)
+	}
+}
+
+func TestPairwise_EqualRankings(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballots := []*models.Ballot{
+		models.NewTieredBallot(pollID, "user1", [][]int{{0, 1}, {2}}),
+		models.NewTieredBallot(pollID, "user2", [][]int{{0, 1}, {2}}),
+		models.NewTieredBallot(pollID, "user3", [][]int{{2}, {1}, {0}}),
+	}
+	result, err := models.NewPairwiseResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed pairwiseJSON
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	// Ballots ranking apple and banana equally count for neither in that pair
+	if !cmp.Equal(parsed.Preferences, [][]int{{0, 0, 2}, {1, 0, 2}, {1, 1, 0}}) {
+		t.Error("unexpected preferences:", parsed.Preferences)
+	}
+	if parsed.CondorcetWinner == nil || *parsed.CondorcetWinner != "banana" {
+		t.Error("unexpected Condorcet winner:", parsed.CondorcetWinner)
+	}
+}
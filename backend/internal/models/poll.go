@@ -1,10 +1,18 @@
 package models
 
 import (
+	cryptorand "crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
+	"math/rand/v2"
 	"slices"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -15,18 +23,332 @@ import (
 type Poll struct {
 	pollID, prompt string
 	choices        []string
+	settings       pollSettings
+	// The secret seed for pseudorandom tie-breaking draws, generated when the poll is created
+	// and replaced when it is reopened
+	tieBreakSeed string
+	// The number of times a ballot has been cast or replaced, which is only stored in and
+	// updated by the database
+	ballotVersion int
+	// The weight of each voter, keyed by user ID. Only voters on the roll can vote in polls that
+	// have one, and it is kept private like the tie-breaking seed.
+	voterRoll map[string]float64
+	// The hexadecimal SHA-256 hash of the secret token for managing the poll. The token itself is
+	// only given to the poll's creator and is never stored.
+	adminTokenHash string
 }
 
-// NewPoll creates a new poll with a newly generated poll ID.
-func NewPoll(prompt string, choices []string) *Poll {
-	return &Poll{uuid.New().String(), prompt, choices}
+// pollSettings holds the optional settings of a poll, where zero values mean the defaults. The
+// fields are exported so that the struct can be embedded in the auxiliary structs used for
+// marshaling.
+type pollSettings struct {
+	// The tabulation method
+	Method Method `json:"method,omitempty" dynamodbav:",omitempty"`
+	// The number of choices to elect, where zero means one
+	Seats int `json:"seats,omitempty" dynamodbav:",omitempty"`
+	// The points for each rank, used only by the positional method
+	Points []float64 `json:"points,omitempty" dynamodbav:",omitempty"`
+	// How ties for last place are broken
+	TieBreakPolicy TieBreakPolicy `json:"tieBreakPolicy,omitempty" dynamodbav:",omitempty"`
+	// Whether voters may leave choices unranked
+	AllowPartial bool `json:"allowPartialRankings,omitempty" dynamodbav:",omitempty"`
+	// The fewest choices a voter must rank when partial rankings are allowed, where zero means
+	// one
+	MinRanks int `json:"minRanks,omitempty" dynamodbav:",omitempty"`
+	// Whether voters may rank names they write in as well as the listed choices
+	AllowWriteIns bool `json:"allowWriteIns,omitempty" dynamodbav:",omitempty"`
+	// Whether instant runoff voting eliminates every choice that can no longer win at once
+	BatchElimination bool `json:"batchElimination,omitempty" dynamodbav:",omitempty"`
+	// The share of the votes a choice must reach to win instant runoff voting, where zero means
+	// a strict majority
+	Threshold float64 `json:"threshold,omitempty" dynamodbav:",omitempty"`
+	// Which ballots the winning threshold is measured against
+	MajorityBasis MajorityBasis `json:"majorityBasis,omitempty" dynamodbav:",omitempty"`
+	// When the poll starts accepting ballots, where nil means as soon as it is created
+	OpensAt *time.Time `json:"opensAt,omitempty" dynamodbav:",omitempty"`
+	// The deadline after which the poll stops accepting ballots, where nil means never
+	ClosesAt *time.Time `json:"closesAt,omitempty" dynamodbav:",omitempty"`
+}
+
+// PollStatus identifies whether a poll is accepting ballots.
+type PollStatus string
+
+const (
+	// StatusDraft means that the poll has not opened yet.
+	StatusDraft PollStatus = "draft"
+	// StatusOpen means that the poll is accepting ballots.
+	StatusOpen PollStatus = "open"
+	// StatusClosed means that the poll's deadline has passed.
+	StatusClosed PollStatus = "closed"
+)
+
+// MajorityBasis identifies which ballots the winning threshold is measured against.
+type MajorityBasis string
+
+const (
+	// BasisContinuing measures the threshold against the ballots still counting for a choice,
+	// leaving out exhausted ballots. This is the default for a strict majority. Since the last
+	// choice in the running has every continuing ballot, some choice always wins.
+	BasisContinuing MajorityBasis = "continuing"
+	// BasisAllBallots measures the threshold against every ballot cast, including exhausted
+	// ballots. This is the default for a poll's own threshold, so that it can go unmet.
+	BasisAllBallots MajorityBasis = "allBallots"
+)
+
+// TieBreakPolicy identifies how a tie for last place is broken when eliminating a choice.
+type TieBreakPolicy string
+
+const (
+	// TieBreakSubPoll simulates a poll between only the tied choices, recursing if there is
+	// another tie and drawing by lot if the tied choices were ranked identically. This is the
+	// default.
+	TieBreakSubPoll TieBreakPolicy = "subPoll"
+	// TieBreakBackward eliminates the tied choice with the fewest votes in the most recent round
+	// in which the tied choices had different numbers of votes.
+	TieBreakBackward TieBreakPolicy = "backward"
+	// TieBreakFirstPreference eliminates the tied choice with the fewest first-choice votes.
+	TieBreakFirstPreference TieBreakPolicy = "firstPreference"
+	// TieBreakLot eliminates one of the tied choices by a seeded pseudorandom draw.
+	TieBreakLot TieBreakPolicy = "lot"
+)
+
+// PollOption configures an optional setting of a poll.
+type PollOption func(*Poll)
+
+// WithMethod sets the method used to tabulate the poll's ballots.
+func WithMethod(method Method) PollOption { return func(p *Poll) { p.settings.Method = method } }
+
+// WithSeats sets the number of choices to elect, which must be one unless the poll uses the
+// single transferable vote method.
+func WithSeats(seats int) PollOption { return func(p *Poll) { p.settings.Seats = seats } }
+
+// WithPoints sets the points awarded for each rank when the poll uses the positional method,
+// where points[0] is for a first choice.
+func WithPoints(points []float64) PollOption { return func(p *Poll) { p.settings.Points = points } }
+
+// WithTieBreakSeed replaces the poll's generated tie-breaking seed.
+func WithTieBreakSeed(seed string) PollOption { return func(p *Poll) { p.tieBreakSeed = seed } }
+
+// WithTieBreakPolicy sets how ties for last place are broken.
+func WithTieBreakPolicy(policy TieBreakPolicy) PollOption {
+	return func(p *Poll) { p.settings.TieBreakPolicy = policy }
+}
+
+// WithPartialRankings allows voters to rank as few as minRanks choices instead of all of them.
+func WithPartialRankings(minRanks int) PollOption {
+	return func(p *Poll) { p.settings.AllowPartial, p.settings.MinRanks = true, minRanks }
+}
+
+// WithWriteIns allows voters to rank names they write in as well as the listed choices.
+func WithWriteIns() PollOption { return func(p *Poll) { p.settings.AllowWriteIns = true } }
+
+// WithBatchElimination makes instant runoff voting eliminate all of the trailing choices whose
+// combined votes are fewer than the next lowest choice's in a single round.
+func WithBatchElimination() PollOption {
+	return func(p *Poll) { p.settings.BatchElimination = true }
+}
+
+// WithVoterRoll restricts voting to the voters on the roll and weights each voter's ballot by
+// their weight on it, keyed by user ID.
+func WithVoterRoll(roll map[string]float64) PollOption {
+	return func(p *Poll) { p.voterRoll = roll }
+}
+
+// WithThreshold sets the share of the votes a choice must reach to win instant runoff voting,
+// such as 2.0/3 for a two-thirds supermajority.
+func WithThreshold(threshold float64) PollOption {
+	return func(p *Poll) { p.settings.Threshold = threshold }
+}
+
+// WithMajorityBasis sets which ballots the winning threshold is measured against.
+func WithMajorityBasis(basis MajorityBasis) PollOption {
+	return func(p *Poll) { p.settings.MajorityBasis = basis }
+}
+
+// WithSchedule sets when the poll opens and closes, where either can be nil to leave that end of
+// the voting window unbounded.
+func WithSchedule(opensAt, closesAt *time.Time) PollOption {
+	return func(p *Poll) { p.settings.OpensAt, p.settings.ClosesAt = opensAt, closesAt }
+}
+
+// NewPoll creates a new poll with a newly generated poll ID and tie-breaking seed.
+func NewPoll(prompt string, choices []string, opts ...PollOption) *Poll {
+	p := &Poll{
+		pollID:       uuid.New().String(),
+		prompt:       prompt,
+		choices:      choices,
+		tieBreakSeed: newTieBreakSeed(),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 // ID gets the poll's poll ID.
 func (p *Poll) ID() string { return p.pollID }
 
-// Validate ensures that the prompt and all choices are non-empty, that there are at least two
-// choices, and that all choices are unique.
+// Method gets the poll's tabulation method, defaulting to instant runoff.
+func (p *Poll) Method() Method {
+	if p.settings.Method == "" {
+		return InstantRunoff
+	}
+	return p.settings.Method
+}
+
+// BallotVersion gets the number of times a ballot has been cast or replaced in the poll, which
+// identifies the state of its ballots.
+func (p *Poll) BallotVersion() int { return p.ballotVersion }
+
+// Weigh sets the weight of each ballot to its voter's weight on the poll's voter roll. Ballots in
+// polls without a voter roll count as one vote each.
+func (p *Poll) Weigh(ballots ...*Ballot) {
+	if len(p.voterRoll) == 0 {
+		return
+	}
+	for _, ballot := range ballots {
+		ballot.weight = p.voterRoll[ballot.userID]
+	}
+}
+
+// TieBreakSeed gets the seed for the poll's pseudorandom tie-breaking draws.
+func (p *Poll) TieBreakSeed() string { return p.tieBreakSeed }
+
+// TieBreakSeedHash gets the hexadecimal SHA-512 hash of the tie-breaking seed. Publishing it
+// commits to the seed without revealing the seed or the SHA-256 key the draws are made with.
+func (p *Poll) TieBreakSeedHash() string {
+	hash := sha512.Sum512([]byte(p.TieBreakSeed()))
+	return hex.EncodeToString(hash[:])
+}
+
+// revealedTieBreakSeed gets the tie-breaking seed once the poll is closed at the provided time,
+// or nil before then, since anyone with the seed could predict the draws that the remaining
+// ballots would lead to. Results that break ties include it alongside the seed's hash, which
+// they always include.
+func (p *Poll) revealedTieBreakSeed(now time.Time) *string {
+	if p.Status(now) != StatusClosed {
+		return nil
+	}
+	return utils.Ref(p.TieBreakSeed())
+}
+
+// tieBreakRand creates a source of randomness for tie-breaking draws. It is ChaCha8 keyed with
+// the SHA-256 hash of the seed, so anyone with the seed can reproduce every draw.
+func (p *Poll) tieBreakRand() *rand.Rand {
+	return rand.New(rand.NewChaCha8(sha256.Sum256([]byte(p.TieBreakSeed()))))
+}
+
+// newTieBreakSeed generates a random hexadecimal seed for tie-breaking draws.
+func newTieBreakSeed() string { return randomHex(16) }
+
+// NewAdminToken generates a new secret token for managing the poll, replacing any previous one.
+// Only the token's hash is kept, so the token must be given to the poll's creator right away.
+func (p *Poll) NewAdminToken() string {
+	token := randomHex(32)
+	p.adminTokenHash = hashAdminToken(token)
+	return token
+}
+
+// IsAdmin reports whether the token is the poll's admin token. Polls created before admin tokens
+// existed cannot be managed by anyone.
+func (p *Poll) IsAdmin(token string) bool {
+	return p.adminTokenHash != "" && subtle.ConstantTimeCompare(
+		[]byte(hashAdminToken(token)), []byte(p.adminTokenHash)) == 1
+}
+
+// hashAdminToken hashes an admin token with SHA-256. A fast hash is enough because the token is
+// random and too long to guess, unlike a password.
+func hashAdminToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// randomHex generates the hexadecimal encoding of the number of random bytes.
+func randomHex(size int) string {
+	b := make([]byte, size)
+	// crypto/rand.Read never returns an error on supported platforms
+	_, _ = cryptorand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// TieBreakPolicy gets how ties for last place are broken, defaulting to a sub-poll.
+func (p *Poll) TieBreakPolicy() TieBreakPolicy {
+	if p.settings.TieBreakPolicy == "" {
+		return TieBreakSubPoll
+	}
+	return p.settings.TieBreakPolicy
+}
+
+// MinRanks gets the fewest choices a voter must rank, which is every choice unless partial
+// rankings are allowed.
+func (p *Poll) MinRanks() int {
+	switch {
+	case !p.settings.AllowPartial:
+		return len(p.choices)
+	case p.settings.MinRanks == 0:
+		return 1
+	default:
+		return p.settings.MinRanks
+	}
+}
+
+// MajorityBasis gets which ballots the winning threshold is measured against, defaulting to every
+// ballot cast if the poll sets a threshold and to the continuing ballots otherwise.
+func (p *Poll) MajorityBasis() MajorityBasis {
+	if p.settings.MajorityBasis == "" && p.settings.Threshold != 0 {
+		return BasisAllBallots
+	}
+	if p.settings.MajorityBasis == "" {
+		return BasisContinuing
+	}
+	return p.settings.MajorityBasis
+}
+
+// Status gets whether the poll is accepting ballots at the provided time. A poll is open from its
+// opening time until, but not including, its closing time.
+func (p *Poll) Status(now time.Time) PollStatus {
+	switch {
+	case p.settings.OpensAt != nil && now.Before(*p.settings.OpensAt):
+		return StatusDraft
+	case p.settings.ClosesAt != nil && !now.Before(*p.settings.ClosesAt):
+		return StatusClosed
+	default:
+		return StatusOpen
+	}
+}
+
+// Edit replaces the poll's prompt and choices. It must only be used before any ballots are cast,
+// since ballots refer to the choices by their indices.
+func (p *Poll) Edit(prompt string, choices []string) { p.prompt, p.choices = prompt, choices }
+
+// Reschedule replaces when the poll opens and closes as of the provided time, where either can be
+// nil to leave that end of the voting window unbounded. Reopening a closed poll replaces its
+// tie-breaking seed, since the old one was revealed when the poll closed.
+func (p *Poll) Reschedule(opensAt, closesAt *time.Time, now time.Time) {
+	wasClosed := p.Status(now) == StatusClosed
+	p.settings.OpensAt, p.settings.ClosesAt = opensAt, closesAt
+	if wasClosed && p.Status(now) != StatusClosed {
+		p.tieBreakSeed = newTieBreakSeed()
+	}
+}
+
+// Close closes the poll at the provided time, moving its deadline forward.
+func (p *Poll) Close(now time.Time) { p.settings.ClosesAt = &now }
+
+// Reopen removes the poll's deadline as of the provided time so that it accepts ballots again
+// until it is closed.
+func (p *Poll) Reopen(now time.Time) { p.Reschedule(p.settings.OpensAt, nil, now) }
+
+// Seats gets the number of choices the poll elects, defaulting to one.
+func (p *Poll) Seats() int {
+	if p.settings.Seats == 0 {
+		return 1
+	}
+	return p.settings.Seats
+}
+
+// Validate ensures that the poll's prompt and choices are usable and that its settings are
+// consistent with each other and with its tabulation method.
 func (p *Poll) Validate() error {
 	if p.prompt == "" {
 		return errors.New("prompt cannot be empty")
@@ -40,9 +362,105 @@ func (p *Poll) Validate() error {
 	if len(p.choices) != utils.NewSet(p.choices...).Len() {
 		return errors.New("choices must be unique")
 	}
+	if _, ok := tabulators[p.Method()]; !ok {
+		return errors.New("unsupported tabulation method")
+	}
+	if !slices.Contains([]TieBreakPolicy{
+		TieBreakSubPoll, TieBreakBackward, TieBreakFirstPreference, TieBreakLot,
+	}, p.TieBreakPolicy()) {
+		return errors.New("unsupported tie-breaking policy")
+	}
+	if p.settings.Seats < 0 {
+		return errors.New("the number of seats must be positive")
+	}
+	if p.Seats() >= len(p.choices) {
+		return errors.New("there must be more choices than seats")
+	}
+	if p.Seats() > 1 && p.Method() != SingleTransferableVote {
+		return errors.New("only single transferable vote polls can have multiple seats")
+	}
+	if p.settings.BatchElimination && p.Method() != InstantRunoff {
+		return errors.New("only instant runoff polls can use batch elimination")
+	}
+	if p.settings.MinRanks != 0 && !p.settings.AllowPartial {
+		return errors.New("only polls allowing partial rankings can have a minimum number of ranks")
+	}
+	if p.settings.MinRanks < 0 || p.settings.MinRanks > len(p.choices) {
+		return errors.New("the minimum number of ranks must be between 1 and the number of choices")
+	}
+	if err := p.validateThreshold(); err != nil {
+		return err
+	}
+	if p.settings.OpensAt != nil && p.settings.ClosesAt != nil &&
+		!p.settings.ClosesAt.After(*p.settings.OpensAt) {
+		return errors.New("the closing time must be after the opening time")
+	}
+	for userID, weight := range p.voterRoll {
+		if userID == "" {
+			return errors.New("user IDs on the voter roll cannot be empty")
+		}
+		if !(weight > 0) || math.IsInf(weight, 1) {
+			return errors.New("voter weights must be positive")
+		}
+	}
+	return p.validatePoints()
+}
+
+// validateThreshold ensures that only instant runoff polls set a winning threshold or majority
+// basis, that the threshold is more than one half and at most one, and that the majority basis is
+// supported.
+func (p *Poll) validateThreshold() error {
+	if (p.settings.Threshold != 0 || p.settings.MajorityBasis != "") &&
+		p.Method() != InstantRunoff {
+		return errors.New("only instant runoff polls can have a winning threshold or majority basis")
+	}
+	if p.settings.Threshold != 0 && !(p.settings.Threshold > 0.5 && p.settings.Threshold <= 1) {
+		return errors.New("the winning threshold must be more than one half and at most one")
+	}
+	if !slices.Contains([]MajorityBasis{BasisContinuing, BasisAllBallots}, p.MajorityBasis()) {
+		return errors.New("unsupported majority basis")
+	}
+	return nil
+}
+
+// validatePoints ensures that positional polls have non-increasing points for every rank and
+// that other polls have none.
+func (p *Poll) validatePoints() error {
+	if p.Method() != Positional {
+		if p.settings.Points != nil {
+			return errors.New("only positional polls can have points")
+		}
+		return nil
+	}
+	if len(p.settings.Points) != len(p.choices) {
+		return errors.New("there must be points for every rank")
+	}
+	for i := 1; i < len(p.settings.Points); i++ {
+		if p.settings.Points[i] > p.settings.Points[i-1] {
+			return errors.New("points cannot increase for lower ranks")
+		}
+	}
 	return nil
 }
 
+// choiceNames looks up the names of the choices at the provided indices.
+func (p *Poll) choiceNames(indices []int) []string {
+	names := make([]string, len(indices))
+	for i, choiceIdx := range indices {
+		names[i] = p.choices[choiceIdx]
+	}
+	return names
+}
+
+// rankingNames looks up the names of the choices in a ranking of tied groups of indices.
+func (p *Poll) rankingNames(ranking [][]int) [][]string {
+	names := make([][]string, len(ranking))
+	for i, group := range ranking {
+		names[i] = p.choiceNames(group)
+	}
+	return names
+}
+
 func (p *Poll) String() string {
 	ret := fmt.Sprintf("Poll with ID %s:\n%s\n", p.pollID[:5]+"... ", p.prompt)
 	for _, c := range p.choices {
@@ -51,28 +469,35 @@ func (p *Poll) String() string {
 	return ret
 }
 
-// MarshalJSON is a custom marshaler that omits the poll ID.
+// MarshalJSON is a custom marshaler that omits the poll ID, tie-breaking seed, voter roll, and
+// admin token hash, and adds the poll's current status.
 func (p *Poll) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
-		Prompt  string   `json:"prompt"`
-		Choices []string `json:"choices"`
-	}{p.prompt, p.choices})
+		Prompt  string     `json:"prompt"`
+		Choices []string   `json:"choices"`
+		Status  PollStatus `json:"status"`
+		pollSettings
+	}{p.prompt, p.choices, p.Status(time.Now()), p.settings})
 }
 
-// UnmarshalJSON is a custom JSON unmarshaler. It generates a poll ID for the new poll.
+// UnmarshalJSON is a custom JSON unmarshaler. It generates a poll ID and tie-breaking seed for
+// the new poll.
 func (p *Poll) UnmarshalJSON(data []byte) error {
 	// Create an auxiliary struct with exported fields to unmarshal the data
 	var aux struct {
-		Prompt  string   `json:"prompt"`
-		Choices []string `json:"choices"`
+		Prompt    string             `json:"prompt"`
+		Choices   []string           `json:"choices"`
+		VoterRoll map[string]float64 `json:"voterRoll"`
+		pollSettings
 	}
 	if err := json.Unmarshal(data, &aux); err != nil {
 		return err
 	}
-	// Create a new poll ID
-	p.pollID = uuid.New().String()
+	// Create a new poll ID and tie-breaking seed
+	p.pollID, p.tieBreakSeed = uuid.New().String(), newTieBreakSeed()
 	// Set the other unmarshaled values back to the main struct
-	p.prompt, p.choices = aux.Prompt, aux.Choices
+	p.prompt, p.choices, p.settings = aux.Prompt, aux.Choices, aux.pollSettings
+	p.voterRoll = aux.VoterRoll
 	return nil
 }
 
@@ -82,7 +507,15 @@ func (p *Poll) MarshalDynamoDBAttributeValue() (types.AttributeValue, error) {
 	m, err := attributevalue.MarshalMap(struct {
 		PollID, Prompt string
 		Choices        []string
-	}{p.pollID, p.prompt, p.choices})
+		TieBreakSeed   string             `dynamodbav:",omitempty"`
+		BallotVersion  int                `dynamodbav:",omitempty"`
+		VoterRoll      map[string]float64 `dynamodbav:",omitempty"`
+		AdminTokenHash string             `dynamodbav:",omitempty"`
+		pollSettings
+	}{
+		p.pollID, p.prompt, p.choices, p.tieBreakSeed, p.ballotVersion, p.voterRoll,
+		p.adminTokenHash, p.settings,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -101,6 +534,11 @@ func (p *Poll) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 	var aux struct {
 		PollID, Prompt string
 		Choices        []string
+		TieBreakSeed   string
+		BallotVersion  int
+		VoterRoll      map[string]float64
+		AdminTokenHash string
+		pollSettings
 	}
 	// Try to unmarshal using the custom struct
 	if err := attributevalue.UnmarshalMap(m.Value, &aux); err != nil {
@@ -108,5 +546,7 @@ func (p *Poll) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
 	}
 	// Set the unmarshaled values back to the main struct
 	p.pollID, p.prompt, p.choices = aux.PollID, aux.Prompt, aux.Choices
+	p.tieBreakSeed, p.ballotVersion, p.settings = aux.TieBreakSeed, aux.BallotVersion, aux.pollSettings
+	p.voterRoll, p.adminTokenHash = aux.VoterRoll, aux.AdminTokenHash
 	return nil
 }
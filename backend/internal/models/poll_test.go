@@ -2,12 +2,15 @@ package models_test
 
 import (
 	"encoding/json"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
 )
 
 func TestValidatePoll_Invalid(t *testing.T) {
@@ -47,6 +50,70 @@ func TestValidatePoll_Invalid(t *testing.T) {
 			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
 		}
 	}
+	optionTests := []struct {
+		errMsg string
+		opts   []models.PollOption
+	}{
+		{"unsupported tabulation method", []models.PollOption{models.WithMethod("dartboard")}},
+		{"unsupported tie-breaking policy", []models.PollOption{
+			models.WithTieBreakPolicy("coinFlip"),
+		}},
+		{"the number of seats must be positive", []models.PollOption{
+			models.WithMethod(models.SingleTransferableVote), models.WithSeats(-1),
+		}},
+		{"there must be more choices than seats", []models.PollOption{
+			models.WithMethod(models.SingleTransferableVote), models.WithSeats(3),
+		}},
+		{"only single transferable vote polls can have multiple seats", []models.PollOption{
+			models.WithSeats(2),
+		}},
+		{"only positional polls can have points", []models.PollOption{
+			models.WithMethod(models.Borda), models.WithPoints([]float64{2, 1, 0}),
+		}},
+		{"there must be points for every rank", []models.PollOption{
+			models.WithMethod(models.Positional), models.WithPoints([]float64{2, 1}),
+		}},
+		{"points cannot increase for lower ranks", []models.PollOption{
+			models.WithMethod(models.Positional), models.WithPoints([]float64{2, 0, 1}),
+		}},
+		{"only instant runoff polls can use batch elimination", []models.PollOption{
+			models.WithMethod(models.Schulze), models.WithBatchElimination(),
+		}},
+		{"the minimum number of ranks must be between 1 and the number of choices",
+			[]models.PollOption{models.WithPartialRankings(4)}},
+		{"the minimum number of ranks must be between 1 and the number of choices",
+			[]models.PollOption{models.WithPartialRankings(-1)}},
+		{"only instant runoff polls can have a winning threshold or majority basis",
+			[]models.PollOption{models.WithMethod(models.Schulze), models.WithThreshold(0.75)}},
+		{"only instant runoff polls can have a winning threshold or majority basis",
+			[]models.PollOption{
+				models.WithMethod(models.Borda), models.WithMajorityBasis(models.BasisAllBallots),
+			}},
+		{"the winning threshold must be more than one half and at most one",
+			[]models.PollOption{models.WithThreshold(0.5)}},
+		{"the winning threshold must be more than one half and at most one",
+			[]models.PollOption{models.WithThreshold(1.5)}},
+		{"unsupported majority basis", []models.PollOption{models.WithMajorityBasis("quorum")}},
+		{"user IDs on the voter roll cannot be empty", []models.PollOption{
+			models.WithVoterRoll(map[string]float64{"": 1}),
+		}},
+		{"voter weights must be positive", []models.PollOption{
+			models.WithVoterRoll(map[string]float64{"user1": 2, "user2": 0}),
+		}},
+		{"voter weights must be positive", []models.PollOption{
+			models.WithVoterRoll(map[string]float64{"user1": math.Inf(1)}),
+		}},
+		{"the closing time must be after the opening time", []models.PollOption{
+			models.WithSchedule(utils.Ref(time.Unix(1000, 0)), utils.Ref(time.Unix(1000, 0))),
+		}},
+	}
+	for _, test := range optionTests {
+		poll := models.NewPoll("What is the best fruit?",
+			[]string{"yuzu", "clementine", "kumquat"}, test.opts...)
+		if err := poll.Validate(); err == nil || err.Error() != test.errMsg {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
 }
 
 func TestValidatePoll_Valid(t *testing.T) {
@@ -63,6 +130,11 @@ func TestValidatePoll_Valid(t *testing.T) {
 		if err := poll.Validate(); err != nil {
 			t.Errorf("expected success, got %v", err)
 		}
+		poll = models.NewPoll(test.prompt, test.choices,
+			models.WithMethod(models.SingleTransferableVote), models.WithSeats(len(test.choices)-1))
+		if err := poll.Validate(); err != nil {
+			t.Errorf("expected success, got %v", err)
+		}
 	}
 }
 
@@ -70,17 +142,37 @@ func TestPollMarshalUnmarshalJSON(t *testing.T) {
 	tests := []struct {
 		prompt     string
 		choices    []string
+		opts       []models.PollOption
 		jsonString string
 	}{
-		{"What is the best fruit?", []string{"yuzu", "clementine"},
-			`{"prompt":"What is the best fruit?","choices":["yuzu","clementine"]}`},
-		{"What is the best vegetable?", []string{"lettuce", "carrot", "green beans"},
-			`{"prompt":"What is the best vegetable?","choices":["lettuce","carrot","green beans"]}`},
-		{"What is the best color?", []string{"red", "blue", "green", "yellow", "orange"},
-			`{"prompt":"What is the best color?","choices":["red","blue","green","yellow","orange"]}`},
+		{"What is the best fruit?", []string{"yuzu", "clementine"}, nil,
+			`{"prompt":"What is the best fruit?","choices":["yuzu","clementine"],"status":"open"}`},
+		{"What is the best vegetable?", []string{"lettuce", "carrot", "green beans"}, nil,
+			`{"prompt":"What is the best vegetable?","choices":["lettuce","carrot","green beans"],` +
+				`"status":"open"}`},
+		{"What is the best color?", []string{"red", "blue", "green", "yellow", "orange"}, nil,
+			`{"prompt":"What is the best color?","choices":["red","blue","green","yellow","orange"],` +
+				`"status":"open"}`},
+		{
+			"What is the best fruit?",
+			[]string{"yuzu", "clementine"},
+			[]models.PollOption{models.WithMethod(models.InstantRunoff)},
+			`{"prompt":"What is the best fruit?","choices":["yuzu","clementine"],"status":"open",` +
+				`"method":"instantRunoff"}`,
+		},
+		{
+			"What is the best fruit?",
+			[]string{"yuzu", "clementine"},
+			[]models.PollOption{models.WithSchedule(
+				utils.Ref(time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)),
+				utils.Ref(time.Date(2025, time.March, 8, 17, 0, 0, 0, time.UTC)),
+			)},
+			`{"prompt":"What is the best fruit?","choices":["yuzu","clementine"],"status":"closed",` +
+				`"opensAt":"2025-03-01T09:00:00Z","closesAt":"2025-03-08T17:00:00Z"}`,
+		},
 	}
 	for _, test := range tests {
-		inPoll := models.NewPoll(test.prompt, test.choices)
+		inPoll := models.NewPoll(test.prompt, test.choices, test.opts...)
 		body, err := json.Marshal(inPoll)
 		if err != nil {
 			t.Error("failed to marshal JSON:", err)
@@ -96,7 +188,7 @@ func TestPollMarshalUnmarshalJSON(t *testing.T) {
 			inPoll,
 			outPoll,
 			cmp.AllowUnexported(models.Poll{}),
-			cmpopts.IgnoreFields(models.Poll{}, "pollID"),
+			cmpopts.IgnoreFields(models.Poll{}, "pollID", "tieBreakSeed"),
 		) {
 			t.Error("unexpected unmarshaled poll:", outPoll)
 		}
@@ -107,13 +199,65 @@ func TestPollMarshalUnmarshalDynamoDBAttributeValue(t *testing.T) {
 	tests := []struct {
 		prompt  string
 		choices []string
+		opts    []models.PollOption
 	}{
-		{"What is the best fruit?", []string{"yuzu", "clementine"}},
-		{"What is the best vegetable?", []string{"lettuce", "carrot", "green beans"}},
-		{"What is the best color?", []string{"red", "blue", "green", "yellow", "orange"}},
+		{"What is the best fruit?", []string{"yuzu", "clementine"}, nil},
+		{"What is the best vegetable?", []string{"lettuce", "carrot", "green beans"}, nil},
+		{"What is the best color?", []string{"red", "blue", "green", "yellow", "orange"}, nil},
+		{
+			"What is the best fruit?",
+			[]string{"yuzu", "clementine"},
+			[]models.PollOption{models.WithMethod(models.InstantRunoff)},
+		},
+		{
+			"What is the best vegetable?",
+			[]string{"lettuce", "carrot", "green beans"},
+			[]models.PollOption{
+				models.WithMethod(models.Positional), models.WithPoints([]float64{3, 1, 0}),
+			},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithTieBreakSeed("0123456789abcdef")},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithTieBreakPolicy(models.TieBreakBackward)},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithPartialRankings(2)},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithWriteIns()},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithVoterRoll(map[string]float64{"user1": 2.5, "user2": 1})},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{
+				models.WithThreshold(2.0 / 3), models.WithMajorityBasis(models.BasisAllBallots),
+			},
+		},
+		{
+			"What is the best color?",
+			[]string{"red", "blue", "green"},
+			[]models.PollOption{models.WithSchedule(
+				nil, utils.Ref(time.Date(2025, time.March, 8, 17, 0, 0, 0, time.UTC)),
+			)},
+		},
 	}
 	for _, test := range tests {
-		inputPoll := models.NewPoll(test.prompt, test.choices)
+		inputPoll := models.NewPoll(test.prompt, test.choices, test.opts...)
 		av, err := attributevalue.MarshalMap(inputPoll)
 		if err != nil {
 			t.Errorf("failed to marshal map: %v", err)
@@ -127,3 +271,97 @@ func TestPollMarshalUnmarshalDynamoDBAttributeValue(t *testing.T) {
 		}
 	}
 }
+
+func TestPollTieBreakSeed(t *testing.T) {
+	first := models.NewPoll("What is the best fruit?", []string{"yuzu", "clementine"})
+	second := models.NewPoll("What is the best fruit?", []string{"yuzu", "clementine"})
+	if len(first.TieBreakSeed()) != 32 || first.TieBreakSeed() == second.TieBreakSeed() {
+		t.Errorf("expected unique generated seeds, got %q and %q",
+			first.TieBreakSeed(), second.TieBreakSeed())
+	}
+}
+
+func TestPollStatus(t *testing.T) {
+	opensAt := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	closesAt := time.Date(2025, time.March, 8, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		opensAt, closesAt *time.Time
+		now               time.Time
+		expected          models.PollStatus
+	}{
+		{nil, nil, opensAt, models.StatusOpen},
+		{&opensAt, &closesAt, opensAt.Add(-time.Second), models.StatusDraft},
+		{&opensAt, &closesAt, opensAt, models.StatusOpen},
+		{&opensAt, &closesAt, closesAt.Add(-time.Second), models.StatusOpen},
+		{&opensAt, &closesAt, closesAt, models.StatusClosed},
+		{&opensAt, nil, closesAt.AddDate(1, 0, 0), models.StatusOpen},
+		{nil, &closesAt, opensAt.AddDate(-1, 0, 0), models.StatusOpen},
+	}
+	for _, test := range tests {
+		poll := models.NewPoll("What is the best fruit?", []string{"yuzu", "clementine"},
+			models.WithSchedule(test.opensAt, test.closesAt))
+		if status := poll.Status(test.now); status != test.expected {
+			t.Errorf("expected status %q at %v, got %q", test.expected, test.now, status)
+		}
+	}
+}
+
+func TestPollAdminToken(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"yuzu", "clementine"})
+	if poll.IsAdmin("") {
+		t.Error("expected a poll without an admin token to have no admin")
+	}
+	token := poll.NewAdminToken()
+	if len(token) != 64 || !poll.IsAdmin(token) || poll.IsAdmin(token[1:]) || poll.IsAdmin("") {
+		t.Error("unexpected admin token check for token:", token)
+	}
+	// Only the hash is stored, and it is kept when the poll is stored and retrieved
+	av, err := attributevalue.MarshalMap(poll)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var stored string
+	if err := attributevalue.Unmarshal(av["AdminTokenHash"], &stored); err != nil ||
+		stored == "" || stored == token {
+		t.Errorf("unexpected stored admin token hash %q", stored)
+	}
+	var retrieved *models.Poll
+	if err := attributevalue.UnmarshalMap(av, &retrieved); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !retrieved.IsAdmin(token) {
+		t.Error("expected the retrieved poll to keep its admin token")
+	}
+	// A new token replaces the previous one
+	if newToken := poll.NewAdminToken(); newToken == token || poll.IsAdmin(token) {
+		t.Error("expected the new admin token to replace the previous one")
+	}
+}
+
+func TestPollCloseReopen(t *testing.T) {
+	opensAt := time.Date(2025, time.March, 1, 9, 0, 0, 0, time.UTC)
+	now := opensAt.AddDate(0, 0, 3)
+	poll := models.NewPoll("What is the best fruit?", []string{"yuzu", "clementine"},
+		models.WithSchedule(&opensAt, utils.Ref(opensAt.AddDate(0, 0, 7))))
+	poll.Close(now)
+	if poll.Status(now) != models.StatusClosed ||
+		poll.Status(now.Add(-time.Second)) != models.StatusOpen {
+		t.Error("expected the poll to close at the provided time")
+	}
+	// Reopening replaces the seed that was revealed when the poll closed
+	seedHash := poll.TieBreakSeedHash()
+	poll.Reopen(now.Add(time.Hour))
+	if poll.Status(now.AddDate(1, 0, 0)) != models.StatusOpen ||
+		poll.Status(opensAt.Add(-time.Second)) != models.StatusDraft {
+		t.Error("expected the reopened poll to keep its opening time and have no deadline")
+	}
+	if poll.TieBreakSeedHash() == seedHash {
+		t.Error("expected the reopened poll to have a new tie-breaking seed")
+	}
+	// Rescheduling a poll that is not closed keeps its seed
+	seedHash = poll.TieBreakSeedHash()
+	poll.Reschedule(nil, utils.Ref(now.AddDate(0, 0, 1)), now)
+	if poll.TieBreakSeedHash() != seedHash {
+		t.Error("expected the rescheduled poll to keep its tie-breaking seed")
+	}
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// Positional scoring methods, which award each choice points based on where each ballot ranks
+// it and elect the choice with the most points.
+const (
+	// Borda awards n - 1 points for a first choice, n - 2 for a second choice, and so on down
+	// to zero for a last choice, where n is the number of choices.
+	Borda Method = "borda"
+	// Dowdall awards 1 point for a first choice, 1/2 for a second choice, 1/3 for a third
+	// choice, and so on.
+	Dowdall Method = "dowdall"
+	// Positional awards the points supplied by the poll creator for each rank.
+	Positional Method = "positional"
+)
+
+type positionalResult struct {
+	poll    *Poll
+	ballots []*Ballot
+	// The points awarded for each rank, where pointsPerRank[0] is for a first choice
+	pointsPerRank []float64
+	// The total points for each choice
+	totals []float64
+	// The choice indices grouped by finishing place, where choices in the same group are tied
+	ranking [][]int
+}
+
+// NewPositionalResult creates a result and performs positional scoring using the provided poll
+// and ballots. The points for each rank depend on the poll's method.
+func NewPositionalResult(poll *Poll, ballots []*Ballot) (*positionalResult, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	res := &positionalResult{
+		poll:          poll,
+		ballots:       ballots,
+		pointsPerRank: poll.pointsPerRank(),
+		totals:        make([]float64, len(poll.choices)),
+	}
+	res.positionalScoring()
+	return res, nil
+}
+
+// pointsPerRank determines the points awarded for each rank based on the poll's method.
+func (p *Poll) pointsPerRank() []float64 {
+	points := make([]float64, len(p.choices))
+	switch p.Method() {
+	case Borda:
+		for i := range points {
+			points[i] = float64(len(points) - 1 - i)
+		}
+	case Dowdall:
+		for i := range points {
+			points[i] = 1 / float64(i+1)
+		}
+	default:
+		copy(points, p.settings.Points)
+	}
+	return points
+}
+
+// Winners returns the indices of the choices with the most points, which is more than one
+// choice only in the case of a tie.
+func (r *positionalResult) Winners() []int { return r.ranking[0] }
+
+// positionalScoring totals the points for each choice and orders the choices by their totals.
+func (r *positionalResult) positionalScoring() {
+	for _, ballot := range r.ballots {
+		rank := 0
+		for _, tier := range ballot.rankOrder {
+			// Equally ranked choices share the average of the points for the ranks they span
+			tierPoints := 0.0
+			for _, points := range r.pointsPerRank[rank : rank+len(tier)] {
+				tierPoints += points
+			}
+			for _, choiceIdx := range tier {
+				r.totals[choiceIdx] += ballot.value() * tierPoints / float64(len(tier))
+			}
+			rank += len(tier)
+		}
+	}
+	order := make([]int, len(r.totals))
+	for i := range order {
+		order[i] = i
+	}
+	// Stable so that tied choices keep their original order
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(r.totals[b], r.totals[a])
+	})
+	for i, choiceIdx := range order {
+		if i > 0 && r.totals[choiceIdx] == r.totals[order[i-1]] {
+			r.ranking[len(r.ranking)-1] = append(r.ranking[len(r.ranking)-1], choiceIdx)
+		} else {
+			r.ranking = append(r.ranking, []int{choiceIdx})
+		}
+	}
+}
+
+// MarshalJSON is a custom marshaler that formats relevant data from the computed result.
+func (r *positionalResult) MarshalJSON() ([]byte, error) {
+	totals := make(map[string]float64, len(r.totals))
+	for choiceIdx, total := range r.totals {
+		totals[r.poll.choices[choiceIdx]] = total
+	}
+	return json.Marshal(&struct {
+		Method         Method             `json:"method"`
+		Prompt         string             `json:"prompt"`
+		TotalVotes     float64            `json:"totalVotes"`
+		WinningChoices []string           `json:"winningChoices"`
+		Ranking        [][]string         `json:"ranking"`
+		PointsPerRank  []float64          `json:"pointsPerRank"`
+		Points         map[string]float64 `json:"points"`
+	}{
+		Method:         r.poll.Method(),
+		Prompt:         r.poll.prompt,
+		TotalVotes:     totalVotes(r.ballots),
+		WinningChoices: r.poll.choiceNames(r.Winners()),
+		Ranking:        r.poll.rankingNames(r.ranking),
+		PointsPerRank:  r.pointsPerRank,
+		Points:         totals,
+	})
+}
@@ -0,0 +1,131 @@
+package models_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func positionalBallots(pollID string) []*models.Ballot {
+	ballotWithRanks := ballotClosure(pollID)
+	return []*models.Ballot{
+		ballotWithRanks([]int{0, 1, 2}),
+		ballotWithRanks([]int{0, 1, 2}),
+		ballotWithRanks([]int{1, 2, 0}),
+		ballotWithRanks([]int{1, 2, 0}),
+		ballotWithRanks([]int{2, 0, 1}),
+	}
+}
+
+func TestPositional(t *testing.T) {
+	tests := []struct {
+		opts          []models.PollOption
+		winners       []int
+		ranking       [][]string
+		pointsPerRank []float64
+		points        map[string]float64
+	}{
+		{
+			[]models.PollOption{models.WithMethod(models.Borda)},
+			[]int{1},
+			[][]string{{"banana"}, {"apple"}, {"clementine"}},
+			[]float64{2, 1, 0},
+			map[string]float64{"apple": 5, "banana": 6, "clementine": 4},
+		},
+		{
+			[]models.PollOption{models.WithMethod(models.Dowdall)},
+			[]int{1},
+			[][]string{{"banana"}, {"apple"}, {"clementine"}},
+			[]float64{1, 1.0 / 2, 1.0 / 3},
+			map[string]float64{"apple": 19.0 / 6, "banana": 10.0 / 3, "clementine": 8.0 / 3},
+		},
+		{
+			// Only first choices count, like a plurality vote
+			[]models.PollOption{
+				models.WithMethod(models.Positional), models.WithPoints([]float64{1, 0, 0}),
+			},
+			[]int{0, 1},
+			[][]string{{"apple", "banana"}, {"clementine"}},
+			[]float64{1, 0, 0},
+			map[string]float64{"apple": 2, "banana": 2, "clementine": 1},
+		},
+	}
+	approx := cmp.Comparer(func(x, y float64) bool { return math.Abs(x-y) < 1e-9 })
+	for _, test := range tests {
+		poll := models.NewPoll("What is the best fruit?",
+			[]string{"apple", "banana", "clementine"}, test.opts...)
+		if err := poll.Validate(); err != nil {
+			t.Fatal(err.Error())
+		}
+		outcome, err := models.Tabulate(poll, positionalBallots(poll.ID()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		if winners := outcome.Winners(); !cmp.Equal(winners, test.winners) {
+			t.Error("unexpected winners:", winners)
+		}
+		body, err := json.Marshal(outcome)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		var parsed struct {
+			Method        models.Method      `json:"method"`
+			TotalVotes    int                `json:"totalVotes"`
+			Ranking       [][]string         `json:"ranking"`
+			PointsPerRank []float64          `json:"pointsPerRank"`
+			Points        map[string]float64 `json:"points"`
+		}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Error(err.Error())
+		}
+		if parsed.Method != poll.Method() || parsed.TotalVotes != 5 {
+			t.Errorf("unexpected summary: %s", body)
+		}
+		if !cmp.Equal(parsed.Ranking, test.ranking) {
+			t.Error("unexpected ranking:", parsed.Ranking)
+		}
+		if !cmp.Equal(parsed.PointsPerRank, test.pointsPerRank, approx) {
+			t.Error("unexpected points per rank:", parsed.PointsPerRank)
+		}
+		if !cmp.Equal(parsed.Points, test.points, approx) {
+			t.Error("unexpected points:", parsed.Points)
+		}
+	}
+}
+
+func TestPositional_EqualRankings(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana", "clementine"},
+		models.WithMethod(models.Borda), models.WithPartialRankings(1))
+	ballots := []*models.Ballot{
+		models.NewTieredBallot(poll.ID(), "user1", [][]int{{0, 1}, {2}}),
+		models.NewTieredBallot(poll.ID(), "user2", [][]int{{2}, {0, 1}}),
+		models.NewTieredBallot(poll.ID(), "user3", [][]int{{0}, {1, 2}}),
+		models.NewTieredBallot(poll.ID(), "user4", [][]int{{1}}),
+	}
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Points map[string]float64 `json:"points"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	// Equally ranked choices share the average of the points for the ranks they span, and
+	// unranked choices get no points
+	expected := map[string]float64{"apple": 4, "banana": 4.5, "clementine": 2.5}
+	if !cmp.Equal(parsed.Points, expected) {
+		t.Error("unexpected points:", parsed.Points)
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{1}) {
+		t.Error("unexpected winners:", winners)
+	}
+}
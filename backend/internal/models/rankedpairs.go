@@ -0,0 +1,147 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// RankedPairs is Tideman's Ranked Pairs Condorcet method.
+const RankedPairs Method = "rankedPairs"
+
+type rankedPairsResult struct {
+	poll    *Poll
+	ballots []*Ballot
+	// Every pairwise majority, strongest first, in the order they were considered for locking
+	majorities []majority
+	// locked[i][j] is true if the majority of choice i over choice j was locked in
+	locked [][]bool
+	// The choice indices grouped by finishing place, where choices in the same group are tied
+	ranking [][]int
+}
+
+// majority records that more ballots rank one choice above another than the reverse, weighing
+// each ballot by its weight.
+type majority struct {
+	winnerIdx, loserIdx    int
+	votesFor, votesAgainst float64
+	// Whether the majority was locked in, as opposed to skipped because it would create a cycle
+	locked bool
+}
+
+// NewRankedPairsResult creates a result and performs the Ranked Pairs method using the provided
+// poll and ballots.
+func NewRankedPairsResult(poll *Poll, ballots []*Ballot) (*rankedPairsResult, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	res := &rankedPairsResult{poll: poll, ballots: ballots}
+	res.sortMajorities(pairwisePreferences(len(poll.choices), ballots))
+	res.lockMajorities()
+	res.computeRanking()
+	return res, nil
+}
+
+// Winners returns the indices of the choices that no locked majority beats, which is more than
+// one choice only in the case of a tie.
+func (r *rankedPairsResult) Winners() []int { return r.ranking[0] }
+
+// sortMajorities finds every pairwise majority and sorts them from strongest to weakest. A
+// majority is stronger if more ballots support it, or if equally many support it but fewer
+// oppose it. Any remaining ties keep the order of the poll's choices so that the result is
+// deterministic.
+func (r *rankedPairsResult) sortMajorities(preferences [][]float64) {
+	for i := range preferences {
+		for j := range preferences {
+			if preferences[i][j] > preferences[j][i] {
+				r.majorities = append(r.majorities, majority{
+					winnerIdx:    i,
+					loserIdx:     j,
+					votesFor:     preferences[i][j],
+					votesAgainst: preferences[j][i],
+				})
+			}
+		}
+	}
+	slices.SortStableFunc(r.majorities, func(a, b majority) int {
+		if a.votesFor != b.votesFor {
+			return cmp.Compare(b.votesFor, a.votesFor)
+		}
+		return cmp.Compare(a.votesAgainst, b.votesAgainst)
+	})
+}
+
+// lockMajorities goes through the sorted majorities and locks in each one that does not create a
+// cycle with the majorities already locked in.
+func (r *rankedPairsResult) lockMajorities() {
+	n := len(r.poll.choices)
+	r.locked = make([][]bool, n)
+	for i := range r.locked {
+		r.locked[i] = make([]bool, n)
+	}
+	for i, m := range r.majorities {
+		// Locking in winner -> loser creates a cycle if the loser already leads to the winner
+		if !r.leadsTo(m.loserIdx, m.winnerIdx, make([]bool, n)) {
+			r.locked[m.winnerIdx][m.loserIdx] = true
+			r.majorities[i].locked = true
+		}
+	}
+}
+
+// leadsTo reports whether there is a path of locked majorities from one choice to another.
+func (r *rankedPairsResult) leadsTo(from, to int, visited []bool) bool {
+	if from == to {
+		return true
+	}
+	visited[from] = true
+	for next, isLocked := range r.locked[from] {
+		if isLocked && !visited[next] && r.leadsTo(next, to, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// computeRanking orders the choices using the locked graph, in which choice i finishes ahead of
+// choice j if it has a locked majority over it.
+func (r *rankedPairsResult) computeRanking() {
+	r.ranking = rankByBeats(len(r.poll.choices), func(i, j int) bool { return r.locked[i][j] })
+}
+
+// MarshalJSON is a custom marshaler that formats relevant data from the computed result. The
+// majorities are listed in the order they were considered so that each step can be audited.
+func (r *rankedPairsResult) MarshalJSON() ([]byte, error) {
+	type majorityJSON struct {
+		Winner       string  `json:"winner"`
+		Loser        string  `json:"loser"`
+		VotesFor     float64 `json:"votesFor"`
+		VotesAgainst float64 `json:"votesAgainst"`
+		Locked       bool    `json:"locked"`
+	}
+	majorities := make([]majorityJSON, len(r.majorities))
+	for i, m := range r.majorities {
+		majorities[i] = majorityJSON{
+			Winner:       r.poll.choices[m.winnerIdx],
+			Loser:        r.poll.choices[m.loserIdx],
+			VotesFor:     m.votesFor,
+			VotesAgainst: m.votesAgainst,
+			Locked:       m.locked,
+		}
+	}
+	return json.Marshal(&struct {
+		Method         Method         `json:"method"`
+		Prompt         string         `json:"prompt"`
+		TotalVotes     float64        `json:"totalVotes"`
+		WinningChoices []string       `json:"winningChoices"`
+		Ranking        [][]string     `json:"ranking"`
+		Majorities     []majorityJSON `json:"majorities"`
+	}{
+		Method:         RankedPairs,
+		Prompt:         r.poll.prompt,
+		TotalVotes:     totalVotes(r.ballots),
+		WinningChoices: r.poll.choiceNames(r.Winners()),
+		Ranking:        r.poll.rankingNames(r.ranking),
+		Majorities:     majorities,
+	})
+}
@@ -0,0 +1,119 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+type majorityJSON struct {
+	Winner       string `json:"winner"`
+	Loser        string `json:"loser"`
+	VotesFor     int    `json:"votesFor"`
+	VotesAgainst int    `json:"votesAgainst"`
+	Locked       bool   `json:"locked"`
+}
+
+func parseRankedPairs(
+	t *testing.T, outcome models.Outcome,
+) (ranking [][]string, majorities []majorityJSON) {
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Method     string         `json:"method"`
+		Ranking    [][]string     `json:"ranking"`
+		Majorities []majorityJSON `json:"majorities"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	if parsed.Method != "rankedPairs" {
+		t.Error("unexpected method:", parsed.Method)
+	}
+	return parsed.Ranking, parsed.Majorities
+}
+
+func TestRankedPairs_Tennessee(t *testing.T) {
+	poll := models.NewPoll("Where should the capital be?",
+		[]string{"Memphis", "Nashville", "Chattanooga", "Knoxville"},
+		models.WithMethod(models.RankedPairs))
+	ballotWithRanks := ballotClosure(poll.ID())
+	var ballots []*models.Ballot
+	for _, group := range []struct {
+		count     int
+		rankOrder []int
+	}{
+		{42, []int{0, 1, 2, 3}},
+		{26, []int{1, 2, 3, 0}},
+		{15, []int{2, 3, 1, 0}},
+		{17, []int{3, 2, 1, 0}},
+	} {
+		for range group.count {
+			ballots = append(ballots, ballotWithRanks(group.rankOrder))
+		}
+	}
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{1}) {
+		t.Error("unexpected winners:", winners)
+	}
+	ranking, majorities := parseRankedPairs(t, outcome)
+	expectedRanking := [][]string{{"Nashville"}, {"Chattanooga"}, {"Knoxville"}, {"Memphis"}}
+	if !cmp.Equal(ranking, expectedRanking) {
+		t.Error("unexpected ranking:", ranking)
+	}
+	expectedMajorities := []majorityJSON{
+		{"Chattanooga", "Knoxville", 83, 17, true},
+		{"Nashville", "Chattanooga", 68, 32, true},
+		{"Nashville", "Knoxville", 68, 32, true},
+		{"Nashville", "Memphis", 58, 42, true},
+		{"Chattanooga", "Memphis", 58, 42, true},
+		{"Knoxville", "Memphis", 58, 42, true},
+	}
+	if !cmp.Equal(majorities, expectedMajorities) {
+		t.Error("unexpected majorities:", majorities)
+	}
+}
+
+func TestRankedPairs_CycleSkipped(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	var ballots []*models.Ballot
+	for _, group := range []struct {
+		count     int
+		rankOrder []int
+	}{
+		{4, []int{0, 1, 2}},
+		{3, []int{1, 2, 0}},
+		{2, []int{2, 0, 1}},
+	} {
+		for range group.count {
+			ballots = append(ballots, ballotWithRanks(group.rankOrder))
+		}
+	}
+	result, err := models.NewRankedPairsResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := result.Winners(); !cmp.Equal(winners, []int{0}) {
+		t.Error("unexpected winners:", winners)
+	}
+	ranking, majorities := parseRankedPairs(t, result)
+	if !cmp.Equal(ranking, [][]string{{"apple"}, {"banana"}, {"clementine"}}) {
+		t.Error("unexpected ranking:", ranking)
+	}
+	expectedMajorities := []majorityJSON{
+		{"banana", "clementine", 7, 2, true},
+		{"apple", "banana", 6, 3, true},
+		{"clementine", "apple", 5, 4, false},
+	}
+	if !cmp.Equal(majorities, expectedMajorities) {
+		t.Error("unexpected majorities:", majorities)
+	}
+}
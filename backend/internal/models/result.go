@@ -1,156 +1,677 @@
 package models
 
 import (
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"math/rand/v2"
 	"slices"
+	"strconv"
+	"time"
 )
 
 type result struct {
 	poll    *Poll
 	ballots []*Ballot
-	// The slice at each index holds the indices of the ballots currently counting for that choice
-	votes                   [][]int
+	// The ballots grouped by rank order, which are counted instead of the individual ballots
+	groups []ballotGroup
+	// The combined weight of all of the ballots
+	totalWeight float64
+	// The slice at each index holds the parcels of ballot groups currently counting for that
+	// choice
+	votes                   [][]parcel
 	winnerIdx, winningRound int
+	// The state of each round, in order, kept for the round-by-round breakdown
+	rounds []round
+	// The reproducible source of randomness for tie-breaking draws, seeded by the poll
+	rng *rand.Rand
+	// The total value of the ballots with no choices left in the running
+	exhausted float64
 }
 
+// ballotGroup is a set of ballots with identical rank orders, which always move together and so
+// can be counted as one.
+type ballotGroup struct {
+	rankOrder []rankTier
+	// The combined weight of the ballots
+	count float64
+}
+
+// parcel is the part of a ballot group's value counting for a single choice. A group's value is
+// only split into several parcels when it ranks choices equally, and a parcel's value is only
+// reduced by an equal ranking or a fractional transfer.
+type parcel struct {
+	groupIdx int
+	value    float64
+}
+
+// round records the vote counts at the start of a round of instant runoff voting and any
+// eliminations that happened at the end of it.
+type round struct {
+	// The number of votes for each choice still in the running, keyed by choice index
+	votes map[int]float64
+	// The total value of the ballots exhausted before the round, which no longer count
+	exhausted    float64
+	eliminations []elimination
+	// Set only in methods that transfer the surplus votes of elected choices
+	surplus *surplusTransfer
+}
+
+// elimination records a choice being eliminated and where its ballots went.
+type elimination struct {
+	choiceIdx int
+	reason    eliminationReason
+	// The other choices that were tied for last place, if any
+	tiedIndices []int
+	// The choices that took part in the random draw, if there was one
+	drawnFrom []int
+	// The number of votes that moved to each other choice, keyed by choice index
+	transfers map[int]float64
+	// The number of votes that had no remaining choice to move to
+	exhausted float64
+}
+
+// eliminationReason explains why a choice was eliminated in a round.
+type eliminationReason string
+
+const (
+	// The choice had strictly the fewest votes
+	reasonLastPlace eliminationReason = "lastPlace"
+	// The choice was tied for last place and lost the sub-poll amongst the tied choices
+	reasonSubPoll eliminationReason = "subPoll"
+	// The choice was tied for last place and had the fewest votes in the most recent round in
+	// which the tied choices had different numbers of votes
+	reasonPreviousRound eliminationReason = "previousRound"
+	// The choice was tied for last place and had the fewest first-choice votes
+	reasonFirstPreference eliminationReason = "firstPreference"
+	// The choice was tied for last place with identical rankings and lost a seeded random draw
+	reasonRandomDraw eliminationReason = "randomDraw"
+	// The choice was one of the trailing choices whose combined votes were fewer than the next
+	// lowest choice's, so none of them could win
+	reasonBatch eliminationReason = "batch"
+)
+
+// noWinnerIdx is the winner index of a result in which no choice reached the winning threshold.
+const noWinnerIdx = -1
+
 // NewResult creates a result and performs instant runoff voting using the provided poll and
-// ballots.
+// ballots. If no choice reaches the poll's winning threshold, the result has no winner.
 func NewResult(poll *Poll, ballots []*Ballot) (*result, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	res := newTally(poll, ballots)
+	// Compute the result from the constructor
+	res.instantRunoffVoting()
+	return res, nil
+}
+
+// newTally sets up a result for counting the provided ballots without computing anything yet.
+func newTally(poll *Poll, ballots []*Ballot) *result {
 	// Initialize votes to empty slices so nil can be used for elimination
-	votes := make([][]int, len(poll.choices))
+	votes := make([][]parcel, len(poll.choices))
 	for i := range votes {
-		votes[i] = make([]int, 0)
+		votes[i] = make([]parcel, 0)
+	}
+	groups := groupBallots(ballots)
+	totalWeight := 0.0
+	for _, group := range groups {
+		totalWeight += group.count
 	}
-	res := &result{
+	return &result{
 		poll:         poll,
 		ballots:      ballots,
+		groups:       groups,
+		totalWeight:  totalWeight,
 		votes:        votes,
-		winnerIdx:    -99,
+		winnerIdx:    noWinnerIdx,
 		winningRound: 0,
+		rng:          poll.tieBreakRand(),
 	}
-	// Compute the result from the constructor
-	if res.instantRunoffVoting(); res.winnerIdx < 0 {
-		return nil, errors.New("the result was not successfully computed")
+}
+
+// groupBallots groups the ballots with identical rank orders, in the order each rank order first
+// appears. Each group counts the combined weight of its ballots.
+func groupBallots(ballots []*Ballot) []ballotGroup {
+	var groups []ballotGroup
+	groupIndices := make(map[string]int)
+	var key []byte
+	for _, ballot := range ballots {
+		key = appendRankOrderKey(key[:0], ballot.rankOrder)
+		// Indexing the map with a converted byte slice does not allocate a string
+		if i, ok := groupIndices[string(key)]; ok {
+			groups[i].count += ballot.value()
+		} else {
+			groupIndices[string(key)] = len(groups)
+			groups = append(groups, ballotGroup{ballot.rankOrder, ballot.value()})
+		}
 	}
-	return res, nil
+	return groups
+}
+
+// appendRankOrderKey appends an encoding of the rank order that is equal for identical rank
+// orders.
+func appendRankOrderKey(key []byte, rankOrder []rankTier) []byte {
+	for _, tier := range rankOrder {
+		for _, choiceIdx := range tier {
+			key = strconv.AppendInt(key, int64(choiceIdx), 10)
+			key = append(key, ',')
+		}
+		key = append(key, '|')
+	}
+	return key
 }
 
 // MarshalJSON is a custom marshaler that formats relevant data from the computed result.
 func (r *result) MarshalJSON() ([]byte, error) {
+	type placingJSON struct {
+		Choice string `json:"choice"`
+		Round  int    `json:"round"`
+	}
+	finishingOrder := r.finishingOrder()
+	ranking := make([]placingJSON, len(finishingOrder))
+	for i, p := range finishingOrder {
+		ranking[i] = placingJSON{r.poll.choices[p.choiceIdx], p.round}
+	}
 	return json.Marshal(&struct {
-		Prompt        string `json:"prompt"`
-		TotalVotes    int    `json:"totalVotes"`
-		WinningVotes  int    `json:"winningVotes"`
-		WinningChoice string `json:"winningChoice"`
-		WinningRound  int    `json:"winningRound"`
+		Method         Method         `json:"method"`
+		Prompt         string         `json:"prompt"`
+		TotalVotes     float64        `json:"totalVotes"`
+		WinningVotes   float64        `json:"winningVotes"`
+		WinningChoice  *string        `json:"winningChoice"`
+		WinningRound   int            `json:"winningRound"`
+		NoWinner       bool           `json:"noWinner,omitempty"`
+		Threshold      float64        `json:"threshold,omitempty"`
+		MajorityBasis  MajorityBasis  `json:"majorityBasis"`
+		Ranking        []placingJSON  `json:"ranking"`
+		TieBreakPolicy TieBreakPolicy `json:"tieBreakPolicy"`
+		SeedHash       string         `json:"tieBreakSeedHash"`
+		TieBreakSeed   *string        `json:"tieBreakSeed,omitempty"`
+		Rounds         []roundJSON    `json:"rounds"`
 	}{
-		Prompt:        r.poll.prompt,
-		TotalVotes:    len(r.ballots),
-		WinningVotes:  len(r.votes[r.winnerIdx]),
-		WinningChoice: r.poll.choices[r.winnerIdx],
-		WinningRound:  r.winningRound,
+		Method:         InstantRunoff,
+		Prompt:         r.poll.prompt,
+		TotalVotes:     totalVotes(r.ballots),
+		WinningVotes:   r.winningVotes(),
+		WinningChoice:  r.winningChoice(),
+		WinningRound:   r.winningRound,
+		NoWinner:       r.winnerIdx == noWinnerIdx,
+		Threshold:      r.poll.settings.Threshold,
+		MajorityBasis:  r.poll.MajorityBasis(),
+		Ranking:        ranking,
+		TieBreakPolicy: r.poll.TieBreakPolicy(),
+		SeedHash:       r.poll.TieBreakSeedHash(),
+		TieBreakSeed:   r.poll.revealedTieBreakSeed(time.Now()),
+		Rounds:         r.roundsJSON(),
+	})
+}
+
+// Winners returns the index of the single winning choice, or nothing if no choice reached the
+// winning threshold.
+func (r *result) Winners() []int {
+	if r.winnerIdx == noWinnerIdx {
+		return nil
+	}
+	return []int{r.winnerIdx}
+}
+
+// winningChoice gets the name of the winning choice, or nil if there is no winner.
+func (r *result) winningChoice() *string {
+	if r.winnerIdx == noWinnerIdx {
+		return nil
+	}
+	return &r.poll.choices[r.winnerIdx]
+}
+
+// winningVotes gets the winning choice's votes in the final round, or zero if there is no
+// winner.
+func (r *result) winningVotes() float64 {
+	if r.winnerIdx == noWinnerIdx {
+		return 0
+	}
+	return r.voteCount(r.winnerIdx)
+}
+
+// placing records a choice's place in the finishing order and the round in which it was decided.
+type placing struct {
+	choiceIdx, round int
+}
+
+// finishingOrder ranks the choices from first to last place. The choices still in the running in
+// the final round come first, from most to fewest votes in that round, which puts any winner
+// first, and then the eliminated choices from last eliminated to first. Choices eliminated in
+// the same round are placed in reverse order of elimination, which for a batch elimination is
+// from most to fewest votes. Choices that were never in the running are left out.
+func (r *result) finishingOrder() []placing {
+	finalRound := len(r.rounds)
+	final := r.rounds[finalRound-1].votes
+	remaining := make([]int, 0, len(final))
+	for choiceIdx := range final {
+		remaining = append(remaining, choiceIdx)
+	}
+	slices.SortFunc(remaining, func(a, b int) int {
+		if c := cmp.Compare(final[b], final[a]); c != 0 {
+			return c
+		}
+		return a - b
 	})
+	order := make([]placing, 0, len(r.poll.choices))
+	for _, choiceIdx := range remaining {
+		order = append(order, placing{choiceIdx, finalRound})
+	}
+	for i := len(r.rounds) - 1; i >= 0; i-- {
+		eliminations := r.rounds[i].eliminations
+		for j := len(eliminations) - 1; j >= 0; j-- {
+			order = append(order, placing{eliminations[j].choiceIdx, i + 1})
+		}
+	}
+	return order
+}
+
+// roundJSON is the JSON representation of a round, with choices referred to by name.
+type roundJSON struct {
+	Round        int                  `json:"round"`
+	Votes        map[string]float64   `json:"votes"`
+	Exhausted    float64              `json:"exhausted,omitempty"`
+	Eliminations []eliminationJSON    `json:"eliminations"`
+	Surplus      *surplusTransferJSON `json:"surplus,omitempty"`
+}
+
+// eliminationJSON is the JSON representation of an elimination, with choices referred to by
+// name.
+type eliminationJSON struct {
+	Choice    string             `json:"choice"`
+	Reason    eliminationReason  `json:"reason"`
+	TiedWith  []string           `json:"tiedWith,omitempty"`
+	DrawnFrom []string           `json:"drawnFrom,omitempty"`
+	Transfers map[string]float64 `json:"transfers"`
+	Exhausted float64            `json:"exhausted,omitempty"`
+}
+
+// roundsJSON converts the recorded rounds into their JSON representation.
+func (r *result) roundsJSON() []roundJSON {
+	out := make([]roundJSON, len(r.rounds))
+	for i, rnd := range r.rounds {
+		out[i] = roundJSON{
+			Round:        i + 1,
+			Votes:        r.namedCounts(rnd.votes),
+			Exhausted:    rnd.exhausted,
+			Eliminations: make([]eliminationJSON, len(rnd.eliminations)),
+		}
+		for j, elim := range rnd.eliminations {
+			var tiedWith []string
+			for _, tiedIdx := range elim.tiedIndices {
+				if tiedIdx != elim.choiceIdx {
+					tiedWith = append(tiedWith, r.poll.choices[tiedIdx])
+				}
+			}
+			out[i].Eliminations[j] = eliminationJSON{
+				Choice:    r.poll.choices[elim.choiceIdx],
+				Reason:    elim.reason,
+				TiedWith:  tiedWith,
+				DrawnFrom: r.poll.choiceNames(elim.drawnFrom),
+				Transfers: r.namedCounts(elim.transfers),
+				Exhausted: elim.exhausted,
+			}
+		}
+		if rnd.surplus != nil {
+			out[i].Surplus = &surplusTransferJSON{
+				Choice:        r.poll.choices[rnd.surplus.choiceIdx],
+				Surplus:       rnd.surplus.surplus,
+				TransferValue: rnd.surplus.transferValue,
+				Transfers:     r.namedCounts(rnd.surplus.transfers),
+				Exhausted:     rnd.surplus.exhausted,
+			}
+		}
+	}
+	return out
+}
+
+// namedCounts converts a map keyed by choice index into one keyed by choice name.
+func (r *result) namedCounts(counts map[int]float64) map[string]float64 {
+	named := make(map[string]float64, len(counts))
+	for choiceIdx, count := range counts {
+		named[r.poll.choices[choiceIdx]] = count
+	}
+	return named
 }
 
 // instantRunoffVoting implements ranked choice voting, specifically the instant runoff method, to
-// calculate the winning choice amongst the submitted ballots.
+// calculate the winning choice amongst the submitted ballots. If the last choice in the running
+// still falls short of the winning threshold, there is no winner.
 func (r *result) instantRunoffVoting() {
-	// Tally first-choice votes
-	for i, ballot := range r.ballots {
-		firstChoiceIdx := ballot.rankOrder[0]
-		r.votes[firstChoiceIdx] = append(r.votes[firstChoiceIdx], i)
-	}
-	// Majority check and elimination
+	r.tallyFirstChoices()
+	// Threshold check and elimination
 	for i := range len(r.poll.choices) { // The number of choice ranks
-		// Check if any choice has a strict majority of votes
-		for j, choiceBallots := range r.votes {
-			if float64(len(choiceBallots))/float64(len(r.ballots)) > 0.5 {
+		// Record the vote counts at the start of the round
+		rnd := r.newRound()
+		// Check if any choice has reached the threshold of the votes in the poll's majority
+		// basis, which excludes exhausted ballots unless the poll sets its own threshold
+		basis := r.totalWeight
+		if r.poll.MajorityBasis() == BasisContinuing {
+			basis -= r.exhausted
+		}
+		for j := range r.votes {
+			if r.reachesThreshold(r.voteCount(j), basis) {
 				r.winnerIdx = j
 				r.winningRound = i + 1
+				r.rounds = append(r.rounds, rnd)
 				return
 			}
 		}
-		// Find the choice(s) in last place
-		minVotes, minIndices := math.MaxInt, make([]int, 0)
-		for j, choiceBallots := range r.votes {
-			if choiceBallots != nil { // Don't consider eliminated choices
-				if len(choiceBallots) < minVotes { // New last place found
-					minVotes, minIndices = len(choiceBallots), []int{j}
-				} else if len(choiceBallots) == minVotes { // Tie for last
-					minIndices = append(minIndices, j)
-				}
-			}
+		// Eliminating the last choice in the running would leave nothing to count
+		if len(rnd.votes) <= 1 {
+			r.rounds = append(r.rounds, rnd)
+			return
 		}
-		// Break ties for last if necessary
-		var loserIdx int
-		if len(minIndices) > 1 {
-			loserIdx = r.breakTiesForLast(minIndices)
+		var defeated []int
+		if r.poll.settings.BatchElimination {
+			defeated = r.defeatedChoices()
+		}
+		if len(defeated) > 1 {
+			// Eliminate every defeated choice at once, so that none of their ballots move to
+			// each other
+			rnd.eliminations = r.eliminateBatch(defeated)
 		} else {
-			loserIdx = minIndices[0]
-		}
-		// Redistribute the losing choice's votes to other choices
-		for _, ballotIdx := range r.votes[loserIdx] {
-			for _, choice := range r.ballots[ballotIdx].rankOrder {
-				// If this choice is not the one being eliminated now and has not been eliminated
-				// in a previous round, redistribute this ballot to the choice
-				if choice != loserIdx && r.votes[choice] != nil {
-					r.votes[choice] = append(r.votes[choice], ballotIdx)
-					break
-				}
+			// Eliminate the choice in last place and redistribute its votes to other choices
+			elim := r.lastPlace()
+			elim.transfers, elim.exhausted = r.transferBallots(elim.choiceIdx)
+			rnd.eliminations = append(rnd.eliminations, elim)
+		}
+		r.rounds = append(r.rounds, rnd)
+	}
+}
+
+// reachesThreshold reports whether the votes are enough to win out of the basis. By default,
+// they must be more than half of it, and otherwise at least the poll's threshold.
+func (r *result) reachesThreshold(votes, basis float64) bool {
+	if basis <= 0 {
+		return false
+	}
+	if r.poll.settings.Threshold == 0 {
+		return votes/basis > 0.5
+	}
+	// Allow for rounding errors, such as in a threshold of 2.0/3
+	return votes >= r.poll.settings.Threshold*basis-voteEpsilon
+}
+
+// defeatedChoices finds the largest group of choices in the running with the fewest votes whose
+// combined votes are fewer than the votes of the next lowest choice. Even if every ballot
+// counting for the group moved to the same one of them, it would still trail that choice, so
+// none of them can win. The choices are returned from fewest to most votes.
+func (r *result) defeatedChoices() []int {
+	counts := r.voteCounts()
+	continuing := make([]int, 0, len(counts))
+	for choiceIdx := range counts {
+		continuing = append(continuing, choiceIdx)
+	}
+	slices.SortFunc(continuing, func(a, b int) int {
+		if c := cmp.Compare(counts[a], counts[b]); c != 0 {
+			return c
+		}
+		return a - b
+	})
+	defeated, combined := 0, 0.0
+	for i := 0; i < len(continuing)-1; i++ {
+		combined += counts[continuing[i]]
+		if combined < counts[continuing[i+1]]-voteEpsilon {
+			defeated = i + 1
+		}
+	}
+	return continuing[:defeated]
+}
+
+// eliminateBatch removes all of the choices from the running before redistributing their votes
+// to the choices that remain.
+func (r *result) eliminateBatch(choiceIndices []int) []elimination {
+	piles := make([][]parcel, len(choiceIndices))
+	for i, choiceIdx := range choiceIndices {
+		piles[i], r.votes[choiceIdx] = r.votes[choiceIdx], nil
+	}
+	eliminations := make([]elimination, len(choiceIndices))
+	for i, choiceIdx := range choiceIndices {
+		eliminations[i] = elimination{choiceIdx: choiceIdx, reason: reasonBatch}
+		eliminations[i].transfers, eliminations[i].exhausted = r.transferPile(piles[i])
+	}
+	return eliminations
+}
+
+// tallyFirstChoices gives each ballot group to its first choice, split equally between its first
+// choices if it ranks several equally.
+func (r *result) tallyFirstChoices() {
+	for i, group := range r.groups {
+		choices, share := r.allocate(parcel{i, group.count})
+		for _, choiceIdx := range choices {
+			r.votes[choiceIdx] = append(r.votes[choiceIdx], parcel{i, share})
+		}
+		if len(choices) == 0 {
+			r.exhausted += group.count
+		}
+	}
+}
+
+// allocate finds the highest-ranked choices in the parcel's rank order that are still in the
+// running and splits the parcel's value equally between them. It returns the choices, which are
+// empty if the ballots are exhausted, and the value for each.
+func (r *result) allocate(p parcel) ([]int, float64) {
+	for _, tier := range r.groups[p.groupIdx].rankOrder {
+		var choices []int
+		for _, choiceIdx := range tier {
+			if r.votes[choiceIdx] != nil {
+				choices = append(choices, choiceIdx)
 			}
 		}
-		// Eliminate the losing choice
-		r.votes[loserIdx] = nil
+		if len(choices) > 0 {
+			return choices, p.value / float64(len(choices))
+		}
+	}
+	return nil, 0
+}
+
+// newRound records the vote counts and exhausted ballots at the start of a round.
+func (r *result) newRound() round {
+	return round{votes: r.voteCounts(), exhausted: r.exhausted}
+}
+
+// voteCount totals the value of the ballots currently counting for the choice.
+func (r *result) voteCount(choiceIdx int) float64 {
+	total := 0.0
+	for _, p := range r.votes[choiceIdx] {
+		total += p.value
+	}
+	return total
+}
+
+// voteCounts totals the votes for each choice still in the running, keyed by choice index.
+func (r *result) voteCounts() map[int]float64 {
+	counts := make(map[int]float64)
+	for choiceIdx, choiceBallots := range r.votes {
+		if choiceBallots != nil {
+			counts[choiceIdx] = r.voteCount(choiceIdx)
+		}
 	}
+	return counts
+}
+
+// lastPlace finds the choice still in the running with the fewest votes, breaking ties for last
+// if necessary. The returned elimination does not have any transfers yet.
+func (r *result) lastPlace() elimination {
+	// Find the choice(s) in last place
+	minVotes, minIndices := math.Inf(1), make([]int, 0)
+	for j, choiceBallots := range r.votes {
+		if choiceBallots != nil { // Don't consider eliminated choices
+			// Counts within voteEpsilon of each other are equal, since split ballots can leave
+			// rounding errors
+			if votes := r.voteCount(j); votes < minVotes-voteEpsilon { // New last place found
+				minVotes, minIndices = votes, []int{j}
+			} else if votes <= minVotes+voteEpsilon { // Tie for last
+				minIndices = append(minIndices, j)
+			}
+		}
+	}
+	// Break ties for last if necessary
+	if len(minIndices) > 1 {
+		elim := r.breakTie(minIndices)
+		elim.tiedIndices = minIndices
+		return elim
+	}
+	return elimination{choiceIdx: minIndices[0], reason: reasonLastPlace}
+}
+
+// transferBallots moves the ballots counting for a choice to the next choices on each ballot that
+// are still in the running, then removes the choice from the running. A ballot moves to the
+// choices ranked equally with the removed choice first if any are still in the running. It
+// returns the value moved to each choice, keyed by choice index, and the value of the ballots
+// with nowhere to move, which become exhausted.
+func (r *result) transferBallots(fromIdx int) (map[int]float64, float64) {
+	// Remove the choice from the running
+	pile := r.votes[fromIdx]
+	r.votes[fromIdx] = nil
+	return r.transferPile(pile)
+}
+
+// transferPile moves each parcel to the next choices on its ballot that are still in the
+// running. It returns the value moved to each choice, keyed by choice index, and the value of
+// the parcels with nowhere to move, which become exhausted.
+func (r *result) transferPile(pile []parcel) (map[int]float64, float64) {
+	transfers, exhausted := make(map[int]float64), 0.0
+	for _, p := range pile {
+		choices, share := r.allocate(p)
+		for _, choiceIdx := range choices {
+			r.votes[choiceIdx] = append(r.votes[choiceIdx], parcel{p.groupIdx, share})
+			transfers[choiceIdx] += share
+		}
+		if len(choices) == 0 {
+			exhausted += p.value
+		}
+	}
+	r.exhausted += exhausted
+	return transfers, exhausted
+}
+
+// breakTie chooses which of the choices tied for last place to eliminate using the poll's
+// tie-breaking policy.
+func (r *result) breakTie(tiedIndices []int) elimination {
+	switch r.poll.TieBreakPolicy() {
+	case TieBreakBackward:
+		return r.breakTieBackward(tiedIndices)
+	case TieBreakFirstPreference:
+		return r.breakTieByFirstPreferences(tiedIndices)
+	case TieBreakLot:
+		return r.drawLot(tiedIndices)
+	default:
+		return r.breakTiesForLast(tiedIndices)
+	}
+}
+
+// breakTieBackward looks back through the previous rounds, starting with the most recent, for
+// one in which the tied choices had different numbers of votes, and eliminates the choice that
+// had the fewest. Choices that are still tied are compared in earlier rounds, and a draw by lot
+// decides if no round tells them apart.
+func (r *result) breakTieBackward(tiedIndices []int) elimination {
+	remaining := tiedIndices
+	for i := len(r.rounds) - 1; i >= 0 && len(remaining) > 1; i-- {
+		remaining = fewest(remaining, func(choiceIdx int) float64 {
+			return r.rounds[i].votes[choiceIdx]
+		})
+	}
+	if len(remaining) > 1 {
+		return r.drawLot(remaining)
+	}
+	return elimination{choiceIdx: remaining[0], reason: reasonPreviousRound}
+}
+
+// breakTieByFirstPreferences eliminates the tied choice that the fewest ballots ranked first,
+// with a draw by lot deciding if they are still tied. Ballots ranking several choices first
+// split their vote equally between them.
+func (r *result) breakTieByFirstPreferences(tiedIndices []int) elimination {
+	firstPreferences := make([]float64, len(r.poll.choices))
+	for _, group := range r.groups {
+		for _, choiceIdx := range group.rankOrder[0] {
+			firstPreferences[choiceIdx] += group.count / float64(len(group.rankOrder[0]))
+		}
+	}
+	remaining := fewest(tiedIndices, func(choiceIdx int) float64 {
+		return firstPreferences[choiceIdx]
+	})
+	if len(remaining) > 1 {
+		return r.drawLot(remaining)
+	}
+	return elimination{choiceIdx: remaining[0], reason: reasonFirstPreference}
+}
+
+// fewest filters the choices down to those with the lowest score, keeping their order. Scores
+// within voteEpsilon of each other are equal.
+func fewest(choiceIndices []int, score func(choiceIdx int) float64) []int {
+	minScore, minIndices := math.Inf(1), make([]int, 0)
+	for _, choiceIdx := range choiceIndices {
+		if s := score(choiceIdx); s < minScore-voteEpsilon {
+			minScore, minIndices = s, []int{choiceIdx}
+		} else if s <= minScore+voteEpsilon {
+			minIndices = append(minIndices, choiceIdx)
+		}
+	}
+	return minIndices
 }
 
 // breakTiesForLast handles cases in instant runoff voting where multiple choices are tied for
-// last place.
-func (r *result) breakTiesForLast(tiedIndices []int) int {
-	tieBreakVotes := make([]int, len(r.votes))
-	// Tally votes using the highest rank that is one of the tied candidates
-	for _, ballot := range r.ballots {
-		for _, choiceIdx := range ballot.rankOrder {
-			if slices.Contains(tiedIndices, choiceIdx) {
-				tieBreakVotes[choiceIdx]++
+// last place. The returned elimination records which choice to eliminate and how it was chosen.
+func (r *result) breakTiesForLast(tiedIndices []int) elimination {
+	tieBreakVotes := make([]float64, len(r.votes))
+	// Tally votes using the highest rank that is one of the tied candidates, split equally
+	// between the tied candidates in that rank
+	for _, group := range r.groups {
+		for _, tier := range group.rankOrder {
+			highest := slices.DeleteFunc(slices.Clone(tier), func(choiceIdx int) bool {
+				return !slices.Contains(tiedIndices, choiceIdx)
+			})
+			for _, choiceIdx := range highest {
+				tieBreakVotes[choiceIdx] += group.count / float64(len(highest))
+			}
+			if len(highest) > 0 {
 				break
 			}
 		}
 	}
 	// Find the choice(s) in last place
-	minVotes, minIndices := math.MaxInt, make([]int, 0)
-	for _, tiedIdx := range tiedIndices {
-		if tieBreakVotes[tiedIdx] < minVotes { // New last place found
-			minVotes, minIndices = tieBreakVotes[tiedIdx], []int{tiedIdx}
-		} else if tieBreakVotes[tiedIdx] == minVotes { // Tie for last again
-			minIndices = append(minIndices, tiedIdx)
-		}
-	}
+	minIndices := fewest(tiedIndices, func(choiceIdx int) float64 {
+		return tieBreakVotes[choiceIdx]
+	})
 	switch len(minIndices) {
 	case 1: // Single minimum found
-		return minIndices[0]
+		return elimination{choiceIdx: minIndices[0], reason: reasonSubPoll}
 	case len(tiedIndices): // No choices were eliminated
-		return minIndices[rand.IntN(len(minIndices))] // Choose randomly to avoid infinite recursion
+		// Draw by lot to avoid infinite recursion
+		return r.drawLot(minIndices)
 	default:
 		return r.breakTiesForLast(minIndices)
 	}
 }
 
+// drawLot eliminates one of the candidates using the poll's seeded source of randomness, so the
+// same ballots always produce the same draw. The candidates are in the order of the poll's
+// choices.
+func (r *result) drawLot(candidates []int) elimination {
+	return elimination{
+		choiceIdx: candidates[r.rng.IntN(len(candidates))],
+		reason:    reasonRandomDraw,
+		drawnFrom: candidates,
+	}
+}
+
 func (r *result) String() string {
-	if r.winnerIdx < 0 {
-		return "The result was not successfully computed. Was the poll valid with at least one " +
-			"corresponding ballot?"
+	if r.winnerIdx == noWinnerIdx {
+		return fmt.Sprintf("\nIn the poll \"%s,\" no choice reached the winning threshold.\n",
+			r.poll.prompt)
 	}
 	return fmt.Sprintf(
-		"\nIn the poll \"%s,\" the choice %q won with %d out of %d votes in round %d.\n",
+		"\nIn the poll \"%s,\" the choice %q won with %g out of %g votes in round %d.\n",
 		r.poll.prompt,
 		r.poll.choices[r.winnerIdx],
-		len(r.votes[r.winnerIdx]),
-		len(r.ballots),
+		r.voteCount(r.winnerIdx),
+		totalVotes(r.ballots),
 		r.winningRound,
 	)
 }
@@ -1,12 +1,18 @@
 package models_test
 
 import (
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"strconv"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/noahkawaguchi/verdict/backend/internal/models"
+	"github.com/noahkawaguchi/verdict/backend/internal/utils"
 )
 
 func threeOptionPoll() (*models.Poll, string) {
@@ -39,6 +45,26 @@ func expectedResultJSON(totalVotes, winningVotes, winningChoiceIdx, winningRound
 	)
 }
 
+// summaryJSON strips everything but the summary fields from a marshaled result so that it can
+// be compared with expectedResultJSON.
+func summaryJSON(t *testing.T, body []byte) string {
+	var summary struct {
+		Prompt        string `json:"prompt"`
+		TotalVotes    int    `json:"totalVotes"`
+		WinningVotes  int    `json:"winningVotes"`
+		WinningChoice string `json:"winningChoice"`
+		WinningRound  int    `json:"winningRound"`
+	}
+	if err := json.Unmarshal(body, &summary); err != nil {
+		t.Error(err.Error())
+	}
+	summaryBody, err := json.Marshal(summary)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	return string(summaryBody)
+}
+
 func TestResult_SimpleMajority(t *testing.T) {
 	poll, pollID := threeOptionPoll()
 	ballotWithRanks := ballotClosure(pollID)
@@ -55,7 +81,7 @@ func TestResult_SimpleMajority(t *testing.T) {
 	if err != nil {
 		t.Error(err.Error())
 	}
-	if string(body) != expectedResultJSON(3, 2, 2, 1) {
+	if summaryJSON(t, body) != expectedResultJSON(3, 2, 2, 1) {
 		t.Errorf("unexpected result: %s", body)
 	}
 }
@@ -78,7 +104,7 @@ func TestResult_Runoff(t *testing.T) {
 	if err != nil {
 		t.Error(err.Error())
 	}
-	if string(body) != expectedResultJSON(5, 3, 1, 2) {
+	if summaryJSON(t, body) != expectedResultJSON(5, 3, 1, 2) {
 		t.Errorf("unexpected result: %s", body)
 	}
 }
@@ -116,7 +142,7 @@ func TestResult_TieForLast(t *testing.T) {
 	if err != nil {
 		t.Error(err.Error())
 	}
-	if string(body) != expectedResultJSON(6, 4, 2, 3) {
+	if summaryJSON(t, body) != expectedResultJSON(6, 4, 2, 3) {
 		t.Errorf("unexpected result: %s", body)
 	}
 }
@@ -161,7 +187,655 @@ func TestResult_InfiniteTieForLast(t *testing.T) {
 	if err != nil {
 		t.Error(err.Error())
 	}
-	if string(body) != expectedResultJSON(8, 5, 3, 3) {
+	if summaryJSON(t, body) != expectedResultJSON(8, 5, 3, 3) {
 		t.Errorf("unexpected result: %s", body)
 	}
 }
+
+func TestResult_Rounds(t *testing.T) {
+	poll, pollID := fourOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 2, 3, 1}),
+		ballotWithRanks([]int{1, 3, 0, 2}),
+		ballotWithRanks([]int{1, 3, 0, 2}),
+		ballotWithRanks([]int{2, 0, 1, 3}),
+		ballotWithRanks([]int{2, 3, 0, 1}),
+		ballotWithRanks([]int{3, 2, 0, 1}),
+	}
+	// Same ballots as TestResult_TieForLast
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Rounds json.RawMessage `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	expected := `[` +
+		`{"round":1,"votes":{"apple":1,"banana":2,"clementine":2,"durian":1},"eliminations":[` +
+		`{"choice":"apple","reason":"subPoll","tiedWith":["durian"],` +
+		`"transfers":{"clementine":1}}]},` +
+		`{"round":2,"votes":{"banana":2,"clementine":3,"durian":1},"eliminations":[` +
+		`{"choice":"durian","reason":"lastPlace","transfers":{"clementine":1}}]},` +
+		`{"round":3,"votes":{"banana":2,"clementine":4},"eliminations":[]}` +
+		`]`
+	if string(parsed.Rounds) != expected {
+		t.Errorf("unexpected rounds: %s", parsed.Rounds)
+	}
+}
+
+func TestResult_RandomDrawRound(t *testing.T) {
+	poll, pollID := fourOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 2, 3, 1}),
+		ballotWithRanks([]int{0, 3, 1, 2}),
+		ballotWithRanks([]int{1, 3, 0, 2}),
+		ballotWithRanks([]int{2, 0, 1, 3}),
+		ballotWithRanks([]int{2, 3, 0, 1}),
+		ballotWithRanks([]int{3, 0, 1, 2}),
+		ballotWithRanks([]int{3, 1, 2, 0}),
+		ballotWithRanks([]int{3, 2, 0, 1}),
+	}
+	// Same ballots as TestResult_InfiniteTieForLast
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Rounds []struct {
+			Eliminations []struct {
+				Reason   string   `json:"reason"`
+				TiedWith []string `json:"tiedWith"`
+			} `json:"eliminations"`
+		} `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	if len(parsed.Rounds) != 3 {
+		t.Fatalf("expected 3 rounds, got %d", len(parsed.Rounds))
+	}
+	if elims := parsed.Rounds[0].Eliminations; len(elims) != 1 || elims[0].Reason != "lastPlace" {
+		t.Errorf("unexpected round 1 eliminations: %+v", elims)
+	}
+	if elims := parsed.Rounds[1].Eliminations; len(elims) != 1 ||
+		elims[0].Reason != "randomDraw" || len(elims[0].TiedWith) != 1 {
+		t.Errorf("unexpected round 2 eliminations: %+v", elims)
+	}
+}
+
+func TestResult_SeededDrawIsReproducible(t *testing.T) {
+	ballotsFor := func(pollID string) []*models.Ballot {
+		ballotWithRanks := ballotClosure(pollID)
+		return []*models.Ballot{
+			ballotWithRanks([]int{0, 2, 3, 1}),
+			ballotWithRanks([]int{0, 3, 1, 2}),
+			ballotWithRanks([]int{1, 3, 0, 2}),
+			ballotWithRanks([]int{2, 0, 1, 3}),
+			ballotWithRanks([]int{2, 3, 0, 1}),
+			ballotWithRanks([]int{3, 0, 1, 2}),
+			ballotWithRanks([]int{3, 1, 2, 0}),
+			ballotWithRanks([]int{3, 2, 0, 1}),
+		}
+	}
+	// Same ballots as TestResult_InfiniteTieForLast, which require a random draw in round 2
+	for _, seed := range []string{"0123456789abcdef", "fedcba9876543210"} {
+		poll := models.NewPoll("What is the best fruit?",
+			[]string{"apple", "banana", "clementine", "durian"}, models.WithTieBreakSeed(seed))
+		var bodies []string
+		for range 5 {
+			result, err := models.NewResult(poll, ballotsFor(poll.ID()))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			body, err := json.Marshal(result)
+			if err != nil {
+				t.Error(err.Error())
+			}
+			bodies = append(bodies, string(body))
+		}
+		for _, body := range bodies[1:] {
+			if body != bodies[0] {
+				t.Errorf("expected the same result every time, got %s and %s", bodies[0], body)
+			}
+		}
+		var parsed struct {
+			SeedHash     string  `json:"tieBreakSeedHash"`
+			TieBreakSeed *string `json:"tieBreakSeed"`
+			Rounds       []struct {
+				Eliminations []struct {
+					Choice    string   `json:"choice"`
+					DrawnFrom []string `json:"drawnFrom"`
+				} `json:"eliminations"`
+			} `json:"rounds"`
+		}
+		if err := json.Unmarshal([]byte(bodies[0]), &parsed); err != nil {
+			t.Fatal(err.Error())
+		}
+		// The seed stays secret while the poll is open, and only its hash is shown
+		hash := sha512.Sum512([]byte(poll.TieBreakSeed()))
+		if parsed.TieBreakSeed != nil || parsed.SeedHash != hex.EncodeToString(hash[:]) {
+			t.Errorf("unexpected tie-break seed and hash: %v, %s", parsed.TieBreakSeed,
+				parsed.SeedHash)
+		}
+		draw := parsed.Rounds[1].Eliminations[0]
+		if !cmp.Equal(draw.DrawnFrom, []string{"apple", "clementine"}) ||
+			(draw.Choice != "apple" && draw.Choice != "clementine") {
+			t.Errorf("unexpected draw: %+v", draw)
+		}
+	}
+}
+
+func TestResult_RevealsSeedOnceClosed(t *testing.T) {
+	ballotsFor := func(pollID string) []*models.Ballot {
+		return []*models.Ballot{
+			models.NewBallot(pollID, "user1", []int{0, 1}),
+			models.NewBallot(pollID, "user2", []int{1, 0}),
+		}
+	}
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	for _, method := range []models.Method{models.InstantRunoff, models.SingleTransferableVote} {
+		for _, test := range []struct {
+			closesAt *time.Time
+			revealed bool
+		}{{nil, false}, {&future, false}, {&past, true}} {
+			poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+				models.WithMethod(method), models.WithTieBreakSeed("0123456789abcdef"),
+				models.WithSchedule(nil, test.closesAt))
+			outcome, err := models.Tabulate(poll, ballotsFor(poll.ID()))
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			body, err := json.Marshal(outcome)
+			if err != nil {
+				t.Fatal(err.Error())
+			}
+			var parsed struct {
+				SeedHash     string  `json:"tieBreakSeedHash"`
+				TieBreakSeed *string `json:"tieBreakSeed"`
+			}
+			if err := json.Unmarshal(body, &parsed); err != nil {
+				t.Fatal(err.Error())
+			}
+			hash := sha512.Sum512([]byte("0123456789abcdef"))
+			revealed := parsed.TieBreakSeed != nil && *parsed.TieBreakSeed == "0123456789abcdef"
+			if parsed.SeedHash != hex.EncodeToString(hash[:]) || revealed != test.revealed {
+				t.Errorf("unexpected %s result for a poll closing at %v: %s",
+					method, test.closesAt, body)
+			}
+		}
+	}
+}
+
+func TestResult_HidesSeedOnceReopened(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithSchedule(nil, utils.Ref(time.Now().Add(-time.Hour))))
+	ballots := []*models.Ballot{models.NewBallot(poll.ID(), "user1", []int{0, 1})}
+	seedFor := func() *string {
+		result, err := models.NewResult(poll, ballots)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := json.Marshal(result)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		var parsed struct {
+			TieBreakSeed *string `json:"tieBreakSeed"`
+		}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Fatal(err.Error())
+		}
+		return parsed.TieBreakSeed
+	}
+	revealed := seedFor()
+	if revealed == nil {
+		t.Fatal("expected the closed poll's seed to be revealed")
+	}
+	// The reopened poll hides its new seed, so the revealed one says nothing about its draws
+	poll.Reopen(time.Now())
+	if seed := seedFor(); seed != nil || poll.TieBreakSeed() == *revealed {
+		t.Errorf("expected a new hidden seed, got %v", seed)
+	}
+}
+
+func TestResult_TieBreakPolicies(t *testing.T) {
+	ballotsFor := func(pollID string) []*models.Ballot {
+		ballotWithRanks := ballotClosure(pollID)
+		var ballots []*models.Ballot
+		for _, group := range []struct {
+			count     int
+			rankOrder []int
+		}{
+			{6, []int{0, 2, 1, 3}},
+			{4, []int{1, 0, 2, 3}},
+			{3, []int{2, 0, 1, 3}},
+			{1, []int{3, 2, 0, 1}},
+		} {
+			for range group.count {
+				ballots = append(ballots, ballotWithRanks(group.rankOrder))
+			}
+		}
+		return ballots
+	}
+	/*
+		Round 1: apple 6, banana 4, clementine 3, durian 1. durian is eliminated and its ballot
+		moves to clementine.
+		Round 2: apple 6, banana 4, clementine 4. banana and clementine tie for last.
+			- A sub-poll between them favors clementine 10 to 4, so banana is eliminated.
+			- banana had more votes than clementine in round 1, so looking backward eliminates
+			  clementine.
+			- banana has more first-choice votes than clementine, so clementine is eliminated.
+	*/
+	tests := []struct {
+		policy     models.TieBreakPolicy
+		eliminated string
+		reason     string
+	}{
+		{"", "banana", "subPoll"},
+		{models.TieBreakSubPoll, "banana", "subPoll"},
+		{models.TieBreakBackward, "clementine", "previousRound"},
+		{models.TieBreakFirstPreference, "clementine", "firstPreference"},
+		{models.TieBreakLot, "", "randomDraw"},
+	}
+	for _, test := range tests {
+		poll := models.NewPoll("What is the best fruit?",
+			[]string{"apple", "banana", "clementine", "durian"},
+			models.WithTieBreakPolicy(test.policy))
+		if err := poll.Validate(); err != nil {
+			t.Fatal(err.Error())
+		}
+		result, err := models.NewResult(poll, ballotsFor(poll.ID()))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := json.Marshal(result)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		var parsed struct {
+			TieBreakPolicy models.TieBreakPolicy `json:"tieBreakPolicy"`
+			WinningChoice  string                `json:"winningChoice"`
+			Rounds         []struct {
+				Eliminations []struct {
+					Choice    string   `json:"choice"`
+					Reason    string   `json:"reason"`
+					TiedWith  []string `json:"tiedWith"`
+					DrawnFrom []string `json:"drawnFrom"`
+				} `json:"eliminations"`
+			} `json:"rounds"`
+		}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Fatal(err.Error())
+		}
+		if parsed.TieBreakPolicy != poll.TieBreakPolicy() || parsed.WinningChoice != "apple" {
+			t.Errorf("unexpected summary: %s", body)
+		}
+		if len(parsed.Rounds) < 2 || len(parsed.Rounds[1].Eliminations) != 1 {
+			t.Fatalf("unexpected rounds: %s", body)
+		}
+		elim := parsed.Rounds[1].Eliminations[0]
+		if elim.Reason != test.reason || len(elim.TiedWith) != 1 ||
+			(test.eliminated != "" && elim.Choice != test.eliminated) {
+			t.Errorf("unexpected elimination with policy %q: %+v", test.policy, elim)
+		}
+		if test.policy == models.TieBreakLot &&
+			!cmp.Equal(elim.DrawnFrom, []string{"banana", "clementine"}) {
+			t.Error("unexpected draw:", elim.DrawnFrom)
+		}
+	}
+}
+
+func TestResult_PartialRankings(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine", "durian"}, models.WithPartialRankings(1))
+	ballotWithRanks := ballotClosure(poll.ID())
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0}),
+		ballotWithRanks([]int{0}),
+		ballotWithRanks([]int{0, 1}),
+		ballotWithRanks([]int{1}),
+		ballotWithRanks([]int{1}),
+		ballotWithRanks([]int{1, 2}),
+		ballotWithRanks([]int{2}),
+		ballotWithRanks([]int{3}),
+		ballotWithRanks([]int{3, 0}),
+	}
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	// apple wins with 4 of the 7 ballots that are not exhausted, short of a majority of all 9
+	if summaryJSON(t, body) != expectedResultJSON(9, 4, 0, 3) {
+		t.Error("unexpected result JSON:", string(body))
+	}
+	var parsed struct {
+		Rounds json.RawMessage `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	expected := `[` +
+		`{"round":1,"votes":{"apple":3,"banana":3,"clementine":1,"durian":2},"eliminations":[` +
+		`{"choice":"clementine","reason":"lastPlace","transfers":{},"exhausted":1}]},` +
+		`{"round":2,"votes":{"apple":3,"banana":3,"durian":2},"exhausted":1,"eliminations":[` +
+		`{"choice":"durian","reason":"lastPlace","transfers":{"apple":1},"exhausted":1}]},` +
+		`{"round":3,"votes":{"apple":4,"banana":3},"exhausted":2,"eliminations":[]}` +
+		`]`
+	if string(parsed.Rounds) != expected {
+		t.Errorf("unexpected rounds: %s", parsed.Rounds)
+	}
+}
+
+func TestResult_FinishingOrder(t *testing.T) {
+	poll, pollID := fourOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	var ballots []*models.Ballot
+	for range 5 {
+		ballots = append(ballots, ballotWithRanks([]int{0, 1, 2, 3}))
+	}
+	for range 4 {
+		ballots = append(ballots, ballotWithRanks([]int{1, 0, 2, 3}))
+	}
+	for range 3 {
+		ballots = append(ballots, ballotWithRanks([]int{2, 0, 1, 3}))
+	}
+	ballots = append(ballots, ballotWithRanks([]int{3, 1, 0, 2}))
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Ranking json.RawMessage `json:"ranking"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	// durian is eliminated in round 1 and clementine in round 2, leaving apple to beat banana
+	expected := `[{"choice":"apple","round":3},{"choice":"banana","round":3},` +
+		`{"choice":"clementine","round":2},{"choice":"durian","round":1}]`
+	if string(parsed.Ranking) != expected {
+		t.Errorf("unexpected ranking: %s", parsed.Ranking)
+	}
+}
+
+func TestResult_WeightedBallots(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana", "clementine"},
+		models.WithVoterRoll(map[string]float64{"user1": 5, "user2": 2, "user3": 2, "user4": 1.5}))
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 2, 0}),
+		models.NewBallot(poll.ID(), "user3", []int{2, 1, 0}),
+		models.NewBallot(poll.ID(), "user4", []int{1, 0, 2}),
+	}
+	poll.Weigh(ballots...)
+	// Ballots recreated from a tally keep their weights
+	tallied, err := models.TallyOf(poll, ballots).Ballots()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, ballots := range [][]*models.Ballot{ballots, tallied} {
+		result, err := models.NewResult(poll, ballots)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := json.Marshal(result)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		var parsed struct {
+			TotalVotes    float64         `json:"totalVotes"`
+			WinningChoice string          `json:"winningChoice"`
+			WinningVotes  float64         `json:"winningVotes"`
+			Rounds        json.RawMessage `json:"rounds"`
+		}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Error(err.Error())
+		}
+		// apple has the most weight in round 1 but not a majority of the 10.5 total votes, and
+		// clementine's weight moves to banana
+		expected := `[` +
+			`{"round":1,"votes":{"apple":5,"banana":3.5,"clementine":2},"eliminations":[` +
+			`{"choice":"clementine","reason":"lastPlace","transfers":{"banana":2}}]},` +
+			`{"round":2,"votes":{"apple":5,"banana":5.5},"eliminations":[]}` +
+			`]`
+		if parsed.TotalVotes != 10.5 || parsed.WinningChoice != "banana" ||
+			parsed.WinningVotes != 5.5 || string(parsed.Rounds) != expected {
+			t.Errorf("unexpected result: %s", body)
+		}
+	}
+	// Merging write-ins keeps the weights
+	writeInPoll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithWriteIns(),
+		models.WithVoterRoll(map[string]float64{"user1": 10, "user2": 1, "user3": 1}))
+	writeInBallots := []*models.Ballot{
+		models.NewWriteInBallot(writeInPoll.ID(), "user1", [][]int{{0}, {2}, {1}},
+			[]string{"kiwi"}),
+		models.NewBallot(writeInPoll.ID(), "user2", []int{1, 0}),
+		models.NewBallot(writeInPoll.ID(), "user3", []int{1, 0}),
+	}
+	writeInPoll.Weigh(writeInBallots...)
+	outcome, err := models.Tabulate(writeInPoll, writeInBallots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{0}) {
+		t.Error("expected apple to win with the heavier ballot, got:", winners)
+	}
+}
+
+func TestResult_Threshold(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []models.PollOption
+		winner       *string
+		finalRound   int
+		finalVotes   string
+		winningVotes float64
+	}{
+		{"strict majority by default", nil, utils.Ref("yuzu"), 1, `{"kumquat":2,"yuzu":3}`, 3},
+		{
+			"supermajority of all ballots by default",
+			[]models.PollOption{models.WithThreshold(2.0 / 3)},
+			nil, 2, `{"yuzu":3}`, 0,
+		},
+		{
+			"supermajority of continuing ballots",
+			[]models.PollOption{
+				models.WithThreshold(2.0 / 3), models.WithMajorityBasis(models.BasisContinuing),
+			},
+			utils.Ref("yuzu"), 2, `{"yuzu":3}`, 3,
+		},
+		{
+			"supermajority of all ballots",
+			[]models.PollOption{
+				models.WithThreshold(2.0 / 3), models.WithMajorityBasis(models.BasisAllBallots),
+			},
+			nil, 2, `{"yuzu":3}`, 0,
+		},
+		{
+			"threshold reached exactly",
+			[]models.PollOption{
+				models.WithThreshold(0.6), models.WithMajorityBasis(models.BasisAllBallots),
+			},
+			utils.Ref("yuzu"), 1, `{"kumquat":2,"yuzu":3}`, 3,
+		},
+	}
+	for _, test := range tests {
+		opts := append([]models.PollOption{models.WithPartialRankings(1)}, test.opts...)
+		poll := models.NewPoll("What is the best fruit?", []string{"yuzu", "kumquat"}, opts...)
+		ballots := []*models.Ballot{
+			models.NewBallot(poll.ID(), "user1", []int{0}),
+			models.NewBallot(poll.ID(), "user2", []int{0}),
+			models.NewBallot(poll.ID(), "user3", []int{0, 1}),
+			models.NewBallot(poll.ID(), "user4", []int{1}),
+			models.NewBallot(poll.ID(), "user5", []int{1}),
+		}
+		result, err := models.NewResult(poll, ballots)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := json.Marshal(result)
+		if err != nil {
+			t.Error(err.Error())
+		}
+		var parsed struct {
+			WinningChoice *string `json:"winningChoice"`
+			WinningVotes  float64 `json:"winningVotes"`
+			NoWinner      bool    `json:"noWinner"`
+			Rounds        []struct {
+				Votes json.RawMessage `json:"votes"`
+			} `json:"rounds"`
+		}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			t.Error(err.Error())
+		}
+		if !cmp.Equal(parsed.WinningChoice, test.winner) ||
+			parsed.NoWinner != (test.winner == nil) ||
+			parsed.WinningVotes != test.winningVotes ||
+			len(parsed.Rounds) != test.finalRound ||
+			string(parsed.Rounds[len(parsed.Rounds)-1].Votes) != test.finalVotes {
+			t.Errorf("%s: unexpected result: %s", test.name, body)
+		}
+	}
+}
+
+func TestResult_EqualRankings(t *testing.T) {
+	poll, pollID := fourOptionPoll()
+	ballots := []*models.Ballot{
+		models.NewTieredBallot(pollID, "user1", [][]int{{0, 1}, {2}, {3}}),
+		models.NewTieredBallot(pollID, "user2", [][]int{{0}, {1}, {2}, {3}}),
+		models.NewTieredBallot(pollID, "user3", [][]int{{2}, {0}, {1}, {3}}),
+		models.NewTieredBallot(pollID, "user4", [][]int{{3}, {2}, {0}, {1}}),
+		models.NewTieredBallot(pollID, "user5", [][]int{{1}, {0}, {2}, {3}}),
+	}
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if summaryJSON(t, body) != expectedResultJSON(5, 3, 0, 3) {
+		t.Error("unexpected result JSON:", string(body))
+	}
+	var parsed struct {
+		Rounds json.RawMessage `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	// The first ballot splits its vote between apple and banana, and its half for banana moves
+	// to apple when banana is eliminated
+	expected := `[` +
+		`{"round":1,"votes":{"apple":1.5,"banana":1.5,"clementine":1,"durian":1},` +
+		`"eliminations":[{"choice":"durian","reason":"subPoll","tiedWith":["clementine"],` +
+		`"transfers":{"clementine":1}}]},` +
+		`{"round":2,"votes":{"apple":1.5,"banana":1.5,"clementine":2},"eliminations":[` +
+		`{"choice":"banana","reason":"subPoll","tiedWith":["apple"],` +
+		`"transfers":{"apple":1.5}}]},` +
+		`{"round":3,"votes":{"apple":3,"clementine":2},"eliminations":[]}` +
+		`]`
+	if string(parsed.Rounds) != expected {
+		t.Errorf("unexpected rounds: %s", parsed.Rounds)
+	}
+}
+
+func TestResult_BatchElimination(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine", "durian"}, models.WithBatchElimination())
+	ballotWithRanks := ballotClosure(poll.ID())
+	var ballots []*models.Ballot
+	for range 5 {
+		ballots = append(ballots, ballotWithRanks([]int{0, 1, 2, 3}))
+	}
+	for range 3 {
+		ballots = append(ballots, ballotWithRanks([]int{1, 0, 2, 3}))
+	}
+	ballots = append(ballots, ballotWithRanks([]int{2, 1, 0, 3}), ballotWithRanks([]int{3, 2, 0, 1}))
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if summaryJSON(t, body) != expectedResultJSON(10, 6, 0, 2) {
+		t.Error("unexpected result JSON:", string(body))
+	}
+	var parsed struct {
+		Rounds json.RawMessage `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	// clementine and durian have 2 votes combined, fewer than banana's 3, so they are both
+	// eliminated at once and the durian ballot skips over clementine
+	expected := `[` +
+		`{"round":1,"votes":{"apple":5,"banana":3,"clementine":1,"durian":1},"eliminations":[` +
+		`{"choice":"clementine","reason":"batch","transfers":{"banana":1}},` +
+		`{"choice":"durian","reason":"batch","transfers":{"apple":1}}]},` +
+		`{"round":2,"votes":{"apple":6,"banana":4},"eliminations":[]}` +
+		`]`
+	if string(parsed.Rounds) != expected {
+		t.Errorf("unexpected rounds: %s", parsed.Rounds)
+	}
+}
+
+// benchmarkBallots creates ballots for a poll with the provided number of choices, drawing each
+// rank order from a fixed number of distinct ones so that the ballots repeat like in a real poll.
+func benchmarkBallots(poll *models.Poll, numChoices, numBallots, numDistinct int) []*models.Ballot {
+	rng := rand.New(rand.NewPCG(1, 2))
+	rankOrders := make([][]int, numDistinct)
+	for i := range rankOrders {
+		rankOrders[i] = rng.Perm(numChoices)
+	}
+	ballots := make([]*models.Ballot, numBallots)
+	for i := range ballots {
+		ballots[i] = models.NewBallot(poll.ID(), strconv.Itoa(i), rankOrders[rng.IntN(numDistinct)])
+	}
+	return ballots
+}
+
+func benchmarkNewResult(b *testing.B, numChoices, numBallots, numDistinct int) {
+	choices := make([]string, numChoices)
+	for i := range choices {
+		choices[i] = "choice " + strconv.Itoa(i)
+	}
+	poll := models.NewPoll("What is the best choice?", choices)
+	ballots := benchmarkBallots(poll, numChoices, numBallots, numDistinct)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := models.NewResult(poll, ballots); err != nil {
+			b.Fatal(err.Error())
+		}
+	}
+}
+
+func BenchmarkNewResult_1kBallots(b *testing.B)   { benchmarkNewResult(b, 10, 1_000, 100) }
+func BenchmarkNewResult_100kBallots(b *testing.B) { benchmarkNewResult(b, 10, 100_000, 500) }
+func BenchmarkNewResult_100kBallots30Choices(b *testing.B) {
+	benchmarkNewResult(b, 30, 100_000, 2_000)
+}
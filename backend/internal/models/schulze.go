@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Schulze is the Schulze (beatpath) Condorcet method.
+const Schulze Method = "schulze"
+
+type schulzeResult struct {
+	poll    *Poll
+	ballots []*Ballot
+	// The weight of the ballots ranking choice i above choice j, at [i][j]
+	preferences [][]float64
+	// The strength of the strongest path from choice i to choice j, at [i][j]
+	strongestPaths [][]float64
+	// The choice indices grouped by finishing place, where choices in the same group are tied
+	ranking [][]int
+}
+
+// NewSchulzeResult creates a result and performs the Schulze method using the provided poll and
+// ballots.
+func NewSchulzeResult(poll *Poll, ballots []*Ballot) (*schulzeResult, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	res := &schulzeResult{
+		poll:        poll,
+		ballots:     ballots,
+		preferences: pairwisePreferences(len(poll.choices), ballots),
+	}
+	res.computeStrongestPaths()
+	res.computeRanking()
+	return res, nil
+}
+
+// Winners returns the indices of the choices that no other choice beats, which is more than one
+// choice only in the case of a tie.
+func (r *schulzeResult) Winners() []int { return r.ranking[0] }
+
+// computeStrongestPaths finds the strength of the strongest path between every pair of choices
+// using a variant of the Floyd-Warshall algorithm. The strength of a path is the strength of its
+// weakest link, where a link from i to j exists only if more voters prefer i to j than j to i.
+func (r *schulzeResult) computeStrongestPaths() {
+	n := len(r.poll.choices)
+	r.strongestPaths = make([][]float64, n)
+	for i := range n {
+		r.strongestPaths[i] = make([]float64, n)
+		for j := range n {
+			if i != j && r.preferences[i][j] > r.preferences[j][i] {
+				r.strongestPaths[i][j] = r.preferences[i][j]
+			}
+		}
+	}
+	for k := range n {
+		for i := range n {
+			if i == k {
+				continue
+			}
+			for j := range n {
+				if j == k || j == i {
+					continue
+				}
+				r.strongestPaths[i][j] = max(
+					r.strongestPaths[i][j],
+					min(r.strongestPaths[i][k], r.strongestPaths[k][j]),
+				)
+			}
+		}
+	}
+}
+
+// computeRanking orders the choices by the Schulze relation, in which choice i finishes ahead of
+// choice j if the strongest path from i to j is stronger than the strongest path from j to i.
+func (r *schulzeResult) computeRanking() {
+	r.ranking = rankByBeats(len(r.poll.choices), func(i, j int) bool {
+		return r.strongestPaths[i][j] > r.strongestPaths[j][i]
+	})
+}
+
+// MarshalJSON is a custom marshaler that formats relevant data from the computed result. The
+// rows and columns of both matrices follow the order of the poll's choices.
+func (r *schulzeResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Method         Method      `json:"method"`
+		Prompt         string      `json:"prompt"`
+		TotalVotes     float64     `json:"totalVotes"`
+		WinningChoices []string    `json:"winningChoices"`
+		Ranking        [][]string  `json:"ranking"`
+		Choices        []string    `json:"choices"`
+		Preferences    [][]float64 `json:"preferences"`
+		StrongestPaths [][]float64 `json:"strongestPaths"`
+	}{
+		Method:         Schulze,
+		Prompt:         r.poll.prompt,
+		TotalVotes:     totalVotes(r.ballots),
+		WinningChoices: r.poll.choiceNames(r.Winners()),
+		Ranking:        r.poll.rankingNames(r.ranking),
+		Choices:        r.poll.choices,
+		Preferences:    r.preferences,
+		StrongestPaths: r.strongestPaths,
+	})
+}
@@ -0,0 +1,146 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+// wikipediaSchulzeBallots builds the 45-voter example election from the Wikipedia article on
+// the Schulze method, with choices A through E at indices 0 through 4.
+func wikipediaSchulzeBallots() (*models.Poll, []*models.Ballot) {
+	poll := models.NewPoll("Who should win?", []string{"A", "B", "C", "D", "E"},
+		models.WithMethod(models.Schulze))
+	ballotWithRanks := ballotClosure(poll.ID())
+	groups := []struct {
+		count     int
+		rankOrder []int
+	}{
+		{5, []int{0, 2, 1, 4, 3}},
+		{5, []int{0, 3, 4, 2, 1}},
+		{8, []int{1, 4, 3, 0, 2}},
+		{3, []int{2, 0, 1, 4, 3}},
+		{7, []int{2, 0, 4, 1, 3}},
+		{2, []int{2, 1, 0, 3, 4}},
+		{7, []int{3, 2, 4, 1, 0}},
+		{8, []int{4, 1, 0, 3, 2}},
+	}
+	var ballots []*models.Ballot
+	for _, group := range groups {
+		for range group.count {
+			ballots = append(ballots, ballotWithRanks(group.rankOrder))
+		}
+	}
+	return poll, ballots
+}
+
+func TestSchulze_WikipediaExample(t *testing.T) {
+	poll, ballots := wikipediaSchulzeBallots()
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{4}) {
+		t.Error("unexpected winners:", winners)
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Method         string     `json:"method"`
+		TotalVotes     int        `json:"totalVotes"`
+		WinningChoices []string   `json:"winningChoices"`
+		Ranking        [][]string `json:"ranking"`
+		Preferences    [][]int    `json:"preferences"`
+		StrongestPaths [][]int    `json:"strongestPaths"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Error(err.Error())
+	}
+	if parsed.Method != "schulze" || parsed.TotalVotes != 45 {
+		t.Errorf("unexpected summary: %s", body)
+	}
+	if !cmp.Equal(parsed.WinningChoices, []string{"E"}) {
+		t.Error("unexpected winning choices:", parsed.WinningChoices)
+	}
+	if !cmp.Equal(parsed.Ranking, [][]string{{"E"}, {"A"}, {"C"}, {"B"}, {"D"}}) {
+		t.Error("unexpected ranking:", parsed.Ranking)
+	}
+	expectedPreferences := [][]int{
+		{0, 20, 26, 30, 22},
+		{25, 0, 16, 33, 18},
+		{19, 29, 0, 17, 24},
+		{15, 12, 28, 0, 14},
+		{23, 27, 21, 31, 0},
+	}
+	if !cmp.Equal(parsed.Preferences, expectedPreferences) {
+		t.Error("unexpected preferences:", parsed.Preferences)
+	}
+	expectedPaths := [][]int{
+		{0, 28, 28, 30, 24},
+		{25, 0, 28, 33, 24},
+		{25, 29, 0, 29, 24},
+		{25, 28, 28, 0, 24},
+		{25, 28, 28, 31, 0},
+	}
+	if !cmp.Equal(parsed.StrongestPaths, expectedPaths) {
+		t.Error("unexpected strongest paths:", parsed.StrongestPaths)
+	}
+}
+
+func TestSchulze_Tie(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 1, 2}),
+		ballotWithRanks([]int{1, 0, 2}),
+	}
+	result, err := models.NewSchulzeResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := result.Winners(); !cmp.Equal(winners, []int{0, 1}) {
+		t.Error("unexpected winners:", winners)
+	}
+}
+
+func TestSchulze_PartialOrder(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	// Choice 0 beats choice 1, but choice 2 neither beats nor is beaten by either
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 1, 2}),
+		ballotWithRanks([]int{2, 0, 1}),
+	}
+	result, err := models.NewSchulzeResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := result.Winners(); !cmp.Equal(winners, []int{0, 2}) {
+		t.Error("unexpected winners:", winners)
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var parsed struct {
+		Ranking [][]string `json:"ranking"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := [][]string{{"apple", "clementine"}, {"banana"}}
+	if !cmp.Equal(parsed.Ranking, expected) {
+		t.Error("unexpected ranking:", parsed.Ranking)
+	}
+}
+
+func TestSchulze_NoBallots(t *testing.T) {
+	poll, _ := threeOptionPoll()
+	if _, err := models.NewSchulzeResult(poll, nil); err == nil {
+		t.Error("expected an error for a poll without ballots")
+	}
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+)
+
+// SingleTransferableVote is the single transferable vote method for electing multiple choices,
+// using the Droop quota and fractional (Gregory) surplus transfers.
+const SingleTransferableVote Method = "singleTransferableVote"
+
+// voteEpsilon absorbs floating point error when comparing fractional vote counts.
+const voteEpsilon = 1e-9
+
+type stvResult struct {
+	// The instant runoff result whose elimination and redistribution logic is reused
+	*result
+	seats int
+	quota float64
+	// Whether the quota is the exact Droop quota, which must be exceeded rather than reached
+	exactQuota bool
+	// The elected choices in order of election
+	elected []electedChoice
+}
+
+// electedChoice records when and how a choice was elected.
+type electedChoice struct {
+	choiceIdx, round int
+	votes            float64
+	// Whether the choice reached the quota, as opposed to filling a remaining seat once the
+	// number of choices still in the running was no more than the number of seats left
+	reachedQuota bool
+}
+
+// surplusTransfer records an elected choice's votes beyond the quota being passed on to the
+// next choices on its ballots.
+type surplusTransfer struct {
+	choiceIdx int
+	surplus   float64
+	// The fraction of its current value that each transferred ballot keeps
+	transferValue float64
+	// The value moved to each other choice, keyed by choice index
+	transfers map[int]float64
+	exhausted float64
+}
+
+// surplusTransferJSON is the JSON representation of a surplus transfer, with choices referred to
+// by name.
+type surplusTransferJSON struct {
+	Choice        string             `json:"choice"`
+	Surplus       float64            `json:"surplus"`
+	TransferValue float64            `json:"transferValue"`
+	Transfers     map[string]float64 `json:"transfers"`
+	Exhausted     float64            `json:"exhausted,omitempty"`
+}
+
+// NewSTVResult creates a result and performs the single transferable vote method using the
+// provided poll and ballots.
+func NewSTVResult(poll *Poll, ballots []*Ballot) (*stvResult, error) {
+	if len(ballots) == 0 {
+		return nil, errors.New("the result was not successfully computed")
+	}
+	// Reuse the instant runoff setup without running instant runoff voting itself
+	tally := newTally(poll, ballots)
+	res := &stvResult{result: tally, seats: poll.Seats()}
+	if len(poll.voterRoll) > 0 {
+		// Fractional weights leave no smallest whole number of votes to reach, so the quota is
+		// the exact share that no more than the number of seats can exceed
+		res.quota, res.exactQuota = tally.totalWeight/float64(poll.Seats()+1), true
+	} else {
+		// The Droop quota is the smallest number of votes that no more than the number of
+		// seats can reach
+		res.quota = math.Floor(tally.totalWeight/float64(poll.Seats()+1)) + 1
+	}
+	res.singleTransferableVote()
+	return res, nil
+}
+
+// Winners returns the indices of the elected choices in order of election.
+func (r *stvResult) Winners() []int {
+	winners := make([]int, len(r.elected))
+	for i, e := range r.elected {
+		winners[i] = e.choiceIdx
+	}
+	return winners
+}
+
+// meetsQuota reports whether the votes are enough to be elected, which means exceeding an exact
+// quota or reaching a whole number quota.
+func (r *stvResult) meetsQuota(votes float64) bool {
+	if r.exactQuota {
+		return votes > r.quota+voteEpsilon
+	}
+	return votes >= r.quota-voteEpsilon
+}
+
+// singleTransferableVote elects choices that reach the quota and transfers their surplus votes,
+// or otherwise eliminates the choice in last place, until all seats are filled.
+func (r *stvResult) singleTransferableVote() {
+	r.tallyFirstChoices()
+	for len(r.elected) < r.seats {
+		// Record the vote counts at the start of the round
+		rnd := r.newRound()
+		roundNum := len(r.rounds) + 1
+		// Order the choices still in the running from most to fewest votes
+		continuing := make([]int, 0, len(rnd.votes))
+		for choiceIdx := range rnd.votes {
+			continuing = append(continuing, choiceIdx)
+		}
+		slices.SortFunc(continuing, func(a, b int) int {
+			if c := cmp.Compare(rnd.votes[b], rnd.votes[a]); c != 0 {
+				return c
+			}
+			return a - b
+		})
+		switch {
+		case r.meetsQuota(rnd.votes[continuing[0]]):
+			// Elect the choice with the most votes and transfer its surplus
+			electedIdx := continuing[0]
+			votes := rnd.votes[electedIdx]
+			r.elected = append(r.elected, electedChoice{electedIdx, roundNum, votes, true})
+			surplus := &surplusTransfer{choiceIdx: electedIdx, surplus: max(votes-r.quota, 0)}
+			surplus.transferValue = surplus.surplus / votes
+			for i := range r.votes[electedIdx] {
+				r.votes[electedIdx][i].value *= surplus.transferValue
+			}
+			surplus.transfers, surplus.exhausted = r.transferBallots(electedIdx)
+			rnd.surplus = surplus
+		case len(continuing) <= r.seats-len(r.elected):
+			// Fill the remaining seats with the remaining choices
+			for _, choiceIdx := range continuing {
+				r.elected = append(r.elected,
+					electedChoice{choiceIdx, roundNum, rnd.votes[choiceIdx], false})
+			}
+		default:
+			// Eliminate the choice in last place and redistribute its votes to other choices
+			elim := r.lastPlace()
+			elim.transfers, elim.exhausted = r.transferBallots(elim.choiceIdx)
+			rnd.eliminations = append(rnd.eliminations, elim)
+		}
+		r.rounds = append(r.rounds, rnd)
+	}
+}
+
+// MarshalJSON is a custom marshaler that formats relevant data from the computed result.
+func (r *stvResult) MarshalJSON() ([]byte, error) {
+	type electedJSON struct {
+		Choice       string  `json:"choice"`
+		Round        int     `json:"round"`
+		Votes        float64 `json:"votes"`
+		ReachedQuota bool    `json:"reachedQuota"`
+	}
+	elected := make([]electedJSON, len(r.elected))
+	for i, e := range r.elected {
+		elected[i] = electedJSON{r.poll.choices[e.choiceIdx], e.round, e.votes, e.reachedQuota}
+	}
+	return json.Marshal(&struct {
+		Method         Method         `json:"method"`
+		Prompt         string         `json:"prompt"`
+		TotalVotes     float64        `json:"totalVotes"`
+		Seats          int            `json:"seats"`
+		Quota          float64        `json:"quota"`
+		ExactQuota     bool           `json:"exactQuota,omitempty"`
+		Elected        []electedJSON  `json:"elected"`
+		TieBreakPolicy TieBreakPolicy `json:"tieBreakPolicy"`
+		SeedHash       string         `json:"tieBreakSeedHash"`
+		TieBreakSeed   *string        `json:"tieBreakSeed,omitempty"`
+		Rounds         []roundJSON    `json:"rounds"`
+	}{
+		Method:         SingleTransferableVote,
+		Prompt:         r.poll.prompt,
+		TotalVotes:     totalVotes(r.ballots),
+		Seats:          r.seats,
+		Quota:          r.quota,
+		ExactQuota:     r.exactQuota,
+		Elected:        elected,
+		TieBreakPolicy: r.poll.TieBreakPolicy(),
+		SeedHash:       r.poll.TieBreakSeedHash(),
+		TieBreakSeed:   r.poll.revealedTieBreakSeed(time.Now()),
+		Rounds:         r.roundsJSON(),
+	})
+}
+
+func (r *stvResult) String() string {
+	names := r.poll.choiceNames(r.Winners())
+	return fmt.Sprintf("\nIn the poll \"%s,\" the choices %q were elected to %d seats.\n",
+		r.poll.prompt, names, r.seats)
+}
@@ -0,0 +1,184 @@
+package models_test
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func TestSTV_SurplusTransfer(t *testing.T) {
+	poll := models.NewPoll("What are the best fruits?",
+		[]string{"apple", "banana", "clementine", "durian"},
+		models.WithMethod(models.SingleTransferableVote), models.WithSeats(2))
+	ballotWithRanks := ballotClosure(poll.ID())
+	var ballots []*models.Ballot
+	for _, group := range []struct {
+		count     int
+		rankOrder []int
+	}{
+		{6, []int{0, 1, 2, 3}},
+		{1, []int{1, 2, 3, 0}},
+		{2, []int{2, 3, 1, 0}},
+		{1, []int{3, 2, 1, 0}},
+	} {
+		for range group.count {
+			ballots = append(ballots, ballotWithRanks(group.rankOrder))
+		}
+	}
+	/*
+		The Droop quota is floor(10 / (2 + 1)) + 1 = 4.
+		Round 1:
+			- apple has 6 votes and is elected with a surplus of 2.
+			- Its 6 ballots move to banana, each now worth 2/6 of a vote.
+		Round 2:
+			- banana has 3 votes, clementine 2, and durian 1. No choice reaches the quota.
+			- durian is eliminated and its ballot moves to clementine.
+		Round 3:
+			- banana and clementine tie with 3 votes each. banana wins the sub-poll between them,
+			  so clementine is eliminated and its 3 ballots move to banana.
+		Round 4:
+			- banana has 6 votes and is elected, filling both seats.
+	*/
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{0, 1}) {
+		t.Error("unexpected winners:", winners)
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	var parsed struct {
+		Method  string  `json:"method"`
+		Seats   int     `json:"seats"`
+		Quota   float64 `json:"quota"`
+		Elected []struct {
+			Choice       string  `json:"choice"`
+			Round        int     `json:"round"`
+			Votes        float64 `json:"votes"`
+			ReachedQuota bool    `json:"reachedQuota"`
+		} `json:"elected"`
+		Rounds []struct {
+			Eliminations []struct {
+				Choice string `json:"choice"`
+				Reason string `json:"reason"`
+			} `json:"eliminations"`
+			Surplus *struct {
+				Choice        string             `json:"choice"`
+				Surplus       float64            `json:"surplus"`
+				TransferValue float64            `json:"transferValue"`
+				Transfers     map[string]float64 `json:"transfers"`
+			} `json:"surplus"`
+		} `json:"rounds"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err.Error())
+	}
+	if parsed.Method != "singleTransferableVote" || parsed.Seats != 2 || parsed.Quota != 4 {
+		t.Errorf("unexpected summary: %s", body)
+	}
+	if len(parsed.Elected) != 2 || len(parsed.Rounds) != 4 {
+		t.Fatalf("unexpected result: %s", body)
+	}
+	for i, expected := range []struct {
+		choice string
+		round  int
+		votes  float64
+	}{{"apple", 1, 6}, {"banana", 4, 6}} {
+		e := parsed.Elected[i]
+		if e.Choice != expected.choice || e.Round != expected.round ||
+			math.Abs(e.Votes-expected.votes) > 1e-9 || !e.ReachedQuota {
+			t.Errorf("unexpected elected choice: %+v", e)
+		}
+	}
+	surplus := parsed.Rounds[0].Surplus
+	if surplus == nil || surplus.Choice != "apple" || surplus.Surplus != 2 ||
+		math.Abs(surplus.TransferValue-1.0/3) > 1e-9 ||
+		math.Abs(surplus.Transfers["banana"]-2) > 1e-9 {
+		t.Errorf("unexpected surplus transfer in round 1: %+v", surplus)
+	}
+	if elims := parsed.Rounds[1].Eliminations; len(elims) != 1 || elims[0].Choice != "durian" {
+		t.Errorf("unexpected eliminations in round 2: %+v", elims)
+	}
+	if elims := parsed.Rounds[2].Eliminations; len(elims) != 1 ||
+		elims[0].Choice != "clementine" || elims[0].Reason != "subPoll" {
+		t.Errorf("unexpected eliminations in round 3: %+v", elims)
+	}
+}
+
+func TestSTV_FractionalWeights(t *testing.T) {
+	poll := models.NewPoll("What are the best fruits?", []string{"apple", "banana", "clementine"},
+		models.WithMethod(models.SingleTransferableVote), models.WithSeats(2),
+		models.WithVoterRoll(map[string]float64{
+			"user1": 0.3, "user2": 0.3, "user3": 0.2, "user4": 0.2,
+		}))
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1, 2}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0, 2}),
+		models.NewBallot(poll.ID(), "user3", []int{2, 0, 1}),
+		models.NewBallot(poll.ID(), "user4", []int{2, 0, 1}),
+	}
+	poll.Weigh(ballots...)
+	/*
+		The exact Droop quota is 1 / (2 + 1) = 1/3, which must be exceeded.
+		Round 1:
+			- clementine has 0.4 votes and is elected with a surplus of 1/15.
+			- Its ballots move to apple, each now worth 1/6 of its weight.
+		Round 2:
+			- apple has 0.3 + 1/15 votes, exceeding the quota, and is elected.
+	*/
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{2, 0}) {
+		t.Error("unexpected winners:", winners)
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var parsed struct {
+		Quota      float64 `json:"quota"`
+		ExactQuota bool    `json:"exactQuota"`
+		Elected    []struct {
+			ReachedQuota bool `json:"reachedQuota"`
+		} `json:"elected"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err.Error())
+	}
+	if math.Abs(parsed.Quota-1.0/3) > 1e-9 || !parsed.ExactQuota || len(parsed.Elected) != 2 ||
+		!parsed.Elected[0].ReachedQuota || !parsed.Elected[1].ReachedQuota {
+		t.Errorf("unexpected result: %s", body)
+	}
+}
+
+func TestSTV_SingleSeatMatchesInstantRunoff(t *testing.T) {
+	poll, pollID := fourOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 2, 3, 1}),
+		ballotWithRanks([]int{1, 3, 0, 2}),
+		ballotWithRanks([]int{1, 3, 0, 2}),
+		ballotWithRanks([]int{2, 0, 1, 3}),
+		ballotWithRanks([]int{2, 3, 0, 1}),
+		ballotWithRanks([]int{3, 2, 0, 1}),
+	}
+	irv, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	stv, err := models.NewSTVResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !cmp.Equal(irv.Winners(), stv.Winners()) {
+		t.Errorf("expected %v, got %v", irv.Winners(), stv.Winners())
+	}
+}
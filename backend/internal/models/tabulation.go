@@ -0,0 +1,73 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Method identifies how a poll's ballots are counted.
+type Method string
+
+// InstantRunoff is the default method, used when a poll does not specify one.
+const InstantRunoff Method = "instantRunoff"
+
+// Outcome is the method-agnostic result of counting a poll's ballots.
+type Outcome interface {
+	json.Marshaler
+	// Winners returns the indices of the winning choices in the order they were decided.
+	Winners() []int
+}
+
+// Tabulator counts the ballots of a poll using a particular method.
+type Tabulator interface {
+	Tabulate(poll *Poll, ballots []*Ballot) (Outcome, error)
+}
+
+// TabulatorFunc adapts an ordinary function to the Tabulator interface.
+type TabulatorFunc func(poll *Poll, ballots []*Ballot) (Outcome, error)
+
+// Tabulate calls f(poll, ballots).
+func (f TabulatorFunc) Tabulate(poll *Poll, ballots []*Ballot) (Outcome, error) {
+	return f(poll, ballots)
+}
+
+// tabulators holds the tabulator for each supported method.
+var tabulators = map[Method]Tabulator{
+	InstantRunoff:          tabulatorFor(NewResult),
+	Schulze:                tabulatorFor(NewSchulzeResult),
+	RankedPairs:            tabulatorFor(NewRankedPairsResult),
+	SingleTransferableVote: tabulatorFor(NewSTVResult),
+	Borda:                  tabulatorFor(NewPositionalResult),
+	Dowdall:                tabulatorFor(NewPositionalResult),
+	Positional:             tabulatorFor(NewPositionalResult),
+}
+
+// tabulatorFor adapts a result constructor to the Tabulator interface.
+func tabulatorFor[T Outcome](newResult func(*Poll, []*Ballot) (T, error)) Tabulator {
+	return TabulatorFunc(func(poll *Poll, ballots []*Ballot) (Outcome, error) {
+		// Avoid returning a nil result wrapped in a non-nil interface
+		res, err := newResult(poll, ballots)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	})
+}
+
+// Tabulate counts the ballots using the poll's chosen method. If the poll allows write-ins,
+// equivalent write-ins are merged into single candidates first, and the outcome lists them.
+func Tabulate(poll *Poll, ballots []*Ballot) (Outcome, error) {
+	tabulator, ok := tabulators[poll.Method()]
+	if !ok {
+		return nil, fmt.Errorf("unknown tabulation method %q", poll.Method())
+	}
+	if !poll.settings.AllowWriteIns {
+		return tabulator.Tabulate(poll, ballots)
+	}
+	poll, ballots, writeIns := mergeWriteIns(poll, ballots)
+	outcome, err := tabulator.Tabulate(poll, ballots)
+	if err != nil {
+		return nil, err
+	}
+	return &writeInOutcome{outcome, writeIns}, nil
+}
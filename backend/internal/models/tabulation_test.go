@@ -0,0 +1,55 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func TestTabulate_DefaultsToInstantRunoff(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballotWithRanks := ballotClosure(pollID)
+	ballots := []*models.Ballot{
+		ballotWithRanks([]int{0, 1, 2}),
+		ballotWithRanks([]int{1, 0, 2}),
+		ballotWithRanks([]int{1, 0, 2}),
+		ballotWithRanks([]int{2, 0, 1}),
+		ballotWithRanks([]int{2, 1, 0}),
+	}
+	if poll.Method() != models.InstantRunoff {
+		t.Error("unexpected default method:", poll.Method())
+	}
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if winners := outcome.Winners(); len(winners) != 1 || winners[0] != 1 {
+		t.Error("unexpected winners:", winners)
+	}
+	result, err := models.NewResult(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	outcomeBody, err := json.Marshal(outcome)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	resultBody, err := json.Marshal(result)
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if string(outcomeBody) != string(resultBody) {
+		t.Errorf("unexpected outcome: %s", outcomeBody)
+	}
+}
+
+func TestTabulate_UnknownMethod(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithMethod("dartboard"))
+	ballots := []*models.Ballot{models.NewBallot(poll.ID(), "user1", []int{0, 1})}
+	if _, err := models.Tabulate(poll, ballots); err == nil ||
+		err.Error() != `unknown tabulation method "dartboard"` {
+		t.Error("unexpected error:", err)
+	}
+}
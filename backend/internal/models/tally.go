@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+)
+
+// Tally is a snapshot of a poll's ballots counted by rank order and weight, which is everything
+// tabulation needs without storing or reading each ballot.
+type Tally struct {
+	pollID string
+	// The poll's ballot version when the snapshot was taken
+	version int
+	// The number of ballots with each rank order, keyed by the ballots' rank keys
+	counts map[string]int
+}
+
+// NewTally creates a tally from counts keyed by rank key.
+func NewTally(pollID string, version int, counts map[string]int) *Tally {
+	return &Tally{pollID, version, counts}
+}
+
+// TallyOf counts the provided ballots by rank order as of the poll's current ballot version.
+func TallyOf(poll *Poll, ballots []*Ballot) *Tally {
+	counts := make(map[string]int)
+	for _, ballot := range ballots {
+		counts[ballot.RankKey()] += ballot.numBallots()
+	}
+	return &Tally{poll.pollID, poll.ballotVersion, counts}
+}
+
+// PollID gets the ID of the poll the tally is for.
+func (t *Tally) PollID() string { return t.pollID }
+
+// Version gets the poll's ballot version when the snapshot was taken.
+func (t *Tally) Version() int { return t.version }
+
+// Counts gets the number of ballots with each rank order, keyed by rank key.
+func (t *Tally) Counts() map[string]int { return t.counts }
+
+// Ballots recreates the counted ballots with their weights but without their user IDs, in a
+// consistent order. Each rank key becomes a single ballot standing for all of the ballots counted
+// under it, so tabulation takes time in proportion to the number of different rank keys rather
+// than the number of ballots.
+func (t *Tally) Ballots() ([]*Ballot, error) {
+	ballots := make([]*Ballot, 0, len(t.counts))
+	for _, key := range slices.Sorted(maps.Keys(t.counts)) {
+		var aux rankKeyJSON
+		if err := json.Unmarshal([]byte(key), &aux); err != nil {
+			return nil, err
+		}
+		ballots = append(ballots, &Ballot{
+			pollID:    t.pollID,
+			rankOrder: aux.RankOrder,
+			writeIns:  aux.WriteIns,
+			weight:    aux.Weight,
+			count:     t.counts[key],
+		})
+	}
+	return ballots, nil
+}
+
+// rankKeyJSON is the JSON representation of the parts of a ballot that are counted.
+type rankKeyJSON struct {
+	RankOrder []rankTier `json:"rankOrder"`
+	WriteIns  []string   `json:"writeIns,omitempty"`
+	Weight    float64    `json:"weight,omitempty"`
+}
+
+// RankKey encodes the ballot's rank order, write-ins, and weight, which are the only parts of a
+// ballot that affect tabulation, so that ballots counted the same way have the same key.
+func (b *Ballot) RankKey() string {
+	// Marshaling ints, strings, and the finite weights of a valid poll cannot fail
+	key, _ := json.Marshal(rankKeyJSON{b.rankOrder, b.writeIns, b.weight})
+	return string(key)
+}
@@ -0,0 +1,64 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func TestTallyOf(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithWriteIns())
+	ballots := []*models.Ballot{
+		models.NewBallot(poll.ID(), "user1", []int{0, 1}),
+		models.NewBallot(poll.ID(), "user2", []int{1, 0}),
+		models.NewBallot(poll.ID(), "user3", []int{0, 1}),
+		models.NewWriteInBallot(poll.ID(), "user4", [][]int{{2}, {0, 1}}, []string{"kiwi"}),
+	}
+	tally := models.TallyOf(poll, ballots)
+	expected := map[string]int{
+		`{"rankOrder":[0,1]}`:                         2,
+		`{"rankOrder":[1,0]}`:                         1,
+		`{"rankOrder":[2,[0,1]],"writeIns":["kiwi"]}`: 1,
+	}
+	if !cmp.Equal(tally.Counts(), expected) {
+		t.Error("unexpected counts:", tally.Counts())
+	}
+	// The tally recreates a single ballot for each rank key, which is tabulated exactly like the
+	// identical ballots it stands for
+	recreated, err := tally.Ballots()
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if len(recreated) != len(expected) {
+		t.Fatal("unexpected number of ballots:", len(recreated))
+	}
+	recreatedTally := models.TallyOf(poll, recreated)
+	if !cmp.Equal(recreatedTally.Counts(), expected) {
+		t.Error("unexpected recreated counts:", recreatedTally.Counts())
+	}
+	for _, method := range []models.Method{models.InstantRunoff, models.Schulze, models.Borda} {
+		poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+			models.WithWriteIns(), models.WithMethod(method))
+		expectedJSON := outcomeJSON(t, poll, ballots)
+		if got := outcomeJSON(t, poll, recreated); got != expectedJSON {
+			t.Errorf("expected %s outcome %s, got %s", method, expectedJSON, got)
+		}
+	}
+}
+
+// outcomeJSON tabulates the ballots for the poll and marshals the outcome.
+func outcomeJSON(t *testing.T, poll *models.Poll, ballots []*models.Ballot) string {
+	t.Helper()
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return string(body)
+}
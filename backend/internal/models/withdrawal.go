@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+// withdrawalResult compares the instant runoff result of a poll with the result of counting the
+// same ballots as if some of the choices had withdrawn before the count.
+type withdrawalResult struct {
+	// The result with every choice in the running
+	original *result
+	// The result with the withdrawn choices eliminated before round 1
+	rerun     *result
+	withdrawn []int
+}
+
+// NewWithdrawalResult performs instant runoff voting on the provided poll and ballots twice: once
+// as usual, and once with the named choices treated as eliminated before round 1, so that every
+// ballot counts for its highest-ranked choice that did not withdraw.
+func NewWithdrawalResult(poll *Poll, ballots []*Ballot, withdrawn []string) (
+	*withdrawalResult, error,
+) {
+	if poll.Method() != InstantRunoff {
+		return nil, errors.New("only instant runoff polls can be rerun with choices withdrawn")
+	}
+	if poll.settings.AllowWriteIns {
+		poll, ballots, _ = mergeWriteIns(poll, ballots)
+	}
+	withdrawnIndices, err := poll.withdrawnIndices(withdrawn)
+	if err != nil {
+		return nil, err
+	}
+	original, err := NewResult(poll, ballots)
+	if err != nil {
+		return nil, err
+	}
+	rerun := newTally(poll, ballots)
+	for _, choiceIdx := range withdrawnIndices {
+		rerun.votes[choiceIdx] = nil
+	}
+	rerun.instantRunoffVoting()
+	return &withdrawalResult{original, rerun, withdrawnIndices}, nil
+}
+
+// withdrawnIndices looks up the indices of the choices to withdraw, which must be unique and
+// leave at least one choice in the running.
+func (p *Poll) withdrawnIndices(names []string) ([]int, error) {
+	if len(names) == 0 {
+		return nil, errors.New("at least one choice must be withdrawn")
+	}
+	indices := make([]int, len(names))
+	for i, name := range names {
+		indices[i] = slices.Index(p.choices, name)
+		if indices[i] < 0 {
+			return nil, errors.New("only the poll's choices can be withdrawn")
+		}
+		if slices.Contains(indices[:i], indices[i]) {
+			return nil, errors.New("each choice can only be withdrawn once")
+		}
+	}
+	if len(indices) == len(p.choices) {
+		return nil, errors.New("at least one choice must remain")
+	}
+	return indices, nil
+}
+
+// WinnerChanged reports whether withdrawing the choices changes the winner.
+func (r *withdrawalResult) WinnerChanged() bool {
+	return r.original.winnerIdx != r.rerun.winnerIdx
+}
+
+// MarshalJSON is a custom marshaler that formats both winners and the rounds of the rerun. The
+// withdrawn choices act as spoilers when they change the winner without including it.
+func (r *withdrawalResult) MarshalJSON() ([]byte, error) {
+	spoiler := r.WinnerChanged() && !slices.Contains(r.withdrawn, r.original.winnerIdx)
+	return json.Marshal(&struct {
+		Prompt         string      `json:"prompt"`
+		TotalVotes     float64     `json:"totalVotes"`
+		Withdrawn      []string    `json:"withdrawn"`
+		OriginalWinner *string     `json:"originalWinner"`
+		Winner         *string     `json:"winner"`
+		WinnerChanged  bool        `json:"winnerChanged"`
+		Spoiler        bool        `json:"spoiler"`
+		WinningRound   int         `json:"winningRound"`
+		Rounds         []roundJSON `json:"rounds"`
+	}{
+		Prompt:         r.rerun.poll.prompt,
+		TotalVotes:     totalVotes(r.rerun.ballots),
+		Withdrawn:      r.rerun.poll.choiceNames(r.withdrawn),
+		OriginalWinner: r.original.winningChoice(),
+		Winner:         r.rerun.winningChoice(),
+		WinnerChanged:  r.WinnerChanged(),
+		Spoiler:        spoiler,
+		WinningRound:   r.rerun.winningRound,
+		Rounds:         r.rerun.roundsJSON(),
+	})
+}
@@ -0,0 +1,99 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+// spoilerBallots are ballots where banana is eliminated first and its votes elect apple, but
+// clementine's voters prefer banana to apple.
+func spoilerBallots(pollID string) []*models.Ballot {
+	ballotWithRanks := ballotClosure(pollID)
+	var ballots []*models.Ballot
+	for range 4 {
+		ballots = append(ballots, ballotWithRanks([]int{0, 1, 2}))
+		ballots = append(ballots, ballotWithRanks([]int{2, 1, 0}))
+	}
+	for range 3 {
+		ballots = append(ballots, ballotWithRanks([]int{1, 0, 2}))
+	}
+	return ballots
+}
+
+func TestWithdrawal(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballots := spoilerBallots(pollID)
+	tests := []struct {
+		name                   string
+		withdrawn              []string
+		winner                 string
+		winnerChanged, spoiler bool
+	}{
+		{"loser withdraws", []string{"banana"}, "apple", false, false},
+		{"spoiler withdraws", []string{"clementine"}, "banana", true, true},
+		{"winner withdraws", []string{"apple"}, "banana", true, false},
+		{"several withdraw", []string{"clementine", "banana"}, "apple", false, false},
+	}
+	for _, test := range tests {
+		res, err := models.NewWithdrawalResult(poll, ballots, test.withdrawn)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		body, err := json.Marshal(res)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		var summary struct {
+			OriginalWinner string `json:"originalWinner"`
+			Winner         string `json:"winner"`
+			WinnerChanged  bool   `json:"winnerChanged"`
+			Spoiler        bool   `json:"spoiler"`
+			WinningRound   int    `json:"winningRound"`
+			Rounds         []struct {
+				Votes map[string]float64 `json:"votes"`
+			} `json:"rounds"`
+		}
+		if err := json.Unmarshal(body, &summary); err != nil {
+			t.Fatal(err.Error())
+		}
+		if summary.OriginalWinner != "apple" || summary.Winner != test.winner ||
+			summary.WinnerChanged != test.winnerChanged || summary.Spoiler != test.spoiler {
+			t.Errorf("%s: unexpected result: %s", test.name, body)
+		}
+		// The withdrawn choices are out of the running from the first round
+		for _, choice := range test.withdrawn {
+			if _, ok := summary.Rounds[0].Votes[choice]; ok {
+				t.Errorf("%s: %s received votes in round 1", test.name, choice)
+			}
+		}
+		if summary.WinningRound != 1 {
+			t.Errorf("%s: expected a winner in round 1, got round %d",
+				test.name, summary.WinningRound)
+		}
+	}
+}
+
+func TestWithdrawal_Error(t *testing.T) {
+	poll, pollID := threeOptionPoll()
+	ballots := spoilerBallots(pollID)
+	schulzePoll := models.NewPoll("What is the best fruit?",
+		[]string{"apple", "banana", "clementine"}, models.WithMethod(models.Schulze))
+	tests := []struct {
+		name      string
+		poll      *models.Poll
+		withdrawn []string
+	}{
+		{"no choices", poll, nil},
+		{"unknown choice", poll, []string{"durian"}},
+		{"duplicate choice", poll, []string{"apple", "apple"}},
+		{"every choice", poll, []string{"apple", "banana", "clementine"}},
+		{"not instant runoff", schulzePoll, []string{"apple"}},
+	}
+	for _, test := range tests {
+		if _, err := models.NewWithdrawalResult(test.poll, ballots, test.withdrawn); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// writeIn records a write-in candidate and every distinct spelling that was merged into it.
+type writeIn struct {
+	name     string
+	spelling []string
+}
+
+// normalizeWriteIn folds case and whitespace so that equivalent write-ins compare equal.
+func normalizeWriteIn(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// validateWriteIns ensures that the ballot only has write-ins if the poll allows them, and that
+// none of its write-ins are empty, equivalent to each other, or equivalent to the poll's choices.
+func (b *Ballot) validateWriteIns(poll *Poll) error {
+	if len(b.writeIns) == 0 {
+		return nil
+	}
+	if !poll.settings.AllowWriteIns {
+		return errors.New("this poll does not allow write-ins")
+	}
+	names := make([]string, 0, len(poll.choices)+len(b.writeIns))
+	for _, choice := range poll.choices {
+		names = append(names, normalizeWriteIn(choice))
+	}
+	for _, name := range b.writeIns {
+		normalized := normalizeWriteIn(name)
+		if normalized == "" {
+			return errors.New("write-ins cannot be empty")
+		}
+		if slices.Contains(names, normalized) {
+			return errors.New("write-ins must be unique and different from the poll's choices")
+		}
+		names = append(names, normalized)
+	}
+	return nil
+}
+
+// mergeWriteIns creates a copy of the poll whose choices include every write-in candidate, along
+// with copies of the ballots that refer to the write-ins by their index in the new choices.
+// Equivalent write-ins merge into a single candidate, and write-ins equivalent to one of the
+// poll's choices merge into that choice. Each candidate is named after its first spelling in
+// ballot order.
+func mergeWriteIns(poll *Poll, ballots []*Ballot) (*Poll, []*Ballot, []writeIn) {
+	merged := *poll
+	merged.choices = slices.Clone(poll.choices)
+	indexOf := make(map[string]int, len(poll.choices))
+	for i, choice := range poll.choices {
+		indexOf[normalizeWriteIn(choice)] = i
+	}
+	var writeIns []writeIn
+	mergedBallots := make([]*Ballot, len(ballots))
+	for i, ballot := range ballots {
+		// Map each write-in on the ballot to the index of its candidate
+		choiceIndices := make([]int, len(ballot.writeIns))
+		for j, name := range ballot.writeIns {
+			normalized := normalizeWriteIn(name)
+			choiceIdx, ok := indexOf[normalized]
+			if !ok {
+				choiceIdx = len(merged.choices)
+				indexOf[normalized] = choiceIdx
+				merged.choices = append(merged.choices, strings.Join(strings.Fields(name), " "))
+			}
+			choiceIndices[j] = choiceIdx
+			// Record the spelling under the candidate's entry
+			k := slices.IndexFunc(writeIns, func(w writeIn) bool {
+				return w.name == merged.choices[choiceIdx]
+			})
+			if k < 0 {
+				k = len(writeIns)
+				writeIns = append(writeIns, writeIn{name: merged.choices[choiceIdx]})
+			}
+			if !slices.Contains(writeIns[k].spelling, name) {
+				writeIns[k].spelling = append(writeIns[k].spelling, name)
+			}
+		}
+		rankOrder := make([]rankTier, len(ballot.rankOrder))
+		for j, tier := range ballot.rankOrder {
+			rankOrder[j] = make(rankTier, len(tier))
+			for k, choiceIdx := range tier {
+				if choiceIdx >= len(poll.choices) {
+					choiceIdx = choiceIndices[choiceIdx-len(poll.choices)]
+				}
+				rankOrder[j][k] = choiceIdx
+			}
+		}
+		mergedBallots[i] = &Ballot{
+			pollID: ballot.pollID, userID: ballot.userID, rankOrder: rankOrder,
+			weight: ballot.weight, count: ballot.count,
+		}
+	}
+	return &merged, mergedBallots, writeIns
+}
+
+// writeInOutcome adds the write-in candidates to the outcome of a poll that allows write-ins.
+// The indices of the winners refer to the merged choices, which list the write-in candidates
+// after the poll's own choices.
+type writeInOutcome struct {
+	Outcome
+	writeIns []writeIn
+}
+
+// MarshalJSON is a custom marshaler that adds the write-in candidates, with the spellings merged
+// into each, to the end of the outcome's own JSON object.
+func (o *writeInOutcome) MarshalJSON() ([]byte, error) {
+	body, err := json.Marshal(o.Outcome)
+	if err != nil {
+		return nil, err
+	}
+	type writeInJSON struct {
+		Name   string   `json:"name"`
+		Merged []string `json:"merged"`
+	}
+	writeIns := make([]writeInJSON, len(o.writeIns))
+	for i, w := range o.writeIns {
+		writeIns[i] = writeInJSON{w.name, w.spelling}
+	}
+	writeInsBody, err := json.Marshal(writeIns)
+	if err != nil {
+		return nil, err
+	}
+	body = append(body[:len(body)-1], `,"writeIns":`...)
+	return append(append(body, writeInsBody...), '}'), nil
+}
@@ -0,0 +1,94 @@
+package models_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/noahkawaguchi/verdict/backend/internal/models"
+)
+
+func TestValidateBallotFor_WriteIns(t *testing.T) {
+	choices := []string{"apple", "banana"}
+	closed := models.NewPoll("What is the best fruit?", choices)
+	open := models.NewPoll("What is the best fruit?", choices, models.WithWriteIns())
+	tests := []struct {
+		errMsg    string
+		poll      *models.Poll
+		rankOrder [][]int
+		writeIns  []string
+	}{
+		{"this poll does not allow write-ins", closed, [][]int{{2}, {0}, {1}}, []string{"kiwi"}},
+		{"write-ins cannot be empty", open, [][]int{{2}, {0}, {1}}, []string{"  "}},
+		{"write-ins must be unique and different from the poll's choices", open,
+			[][]int{{2}, {0}, {1}}, []string{" Apple"}},
+		{"write-ins must be unique and different from the poll's choices", open,
+			[][]int{{2}, {3}, {0}, {1}}, []string{"kiwi", "KIWI"}},
+		{"every write-in must be ranked", open, [][]int{{2}, {0}, {1}}, []string{"kiwi", "lime"}},
+		{"not a valid rank order", open, [][]int{{2}, {4}, {0}, {1}}, []string{"kiwi"}},
+		{"not a valid rank order", open, [][]int{{2}, {0}, {3}}, []string{"kiwi", "lime"}},
+		{"", open, [][]int{{2}, {0}, {1}}, []string{"kiwi"}},
+		{"", open, [][]int{{0}, {1, 3}, {2}}, []string{"kiwi", "lime"}},
+		{"", open, [][]int{{0}, {1}}, nil},
+	}
+	for _, test := range tests {
+		ballot := models.NewWriteInBallot(test.poll.ID(), "user1", test.rankOrder, test.writeIns)
+		err := ballot.ValidateFor(test.poll)
+		if test.errMsg == "" && err != nil {
+			t.Errorf("expected success, got %v", err)
+		}
+		if test.errMsg != "" && (err == nil || err.Error() != test.errMsg) {
+			t.Errorf("expected error with message %q, got %v", test.errMsg, err)
+		}
+	}
+}
+
+func TestTabulate_MergesWriteIns(t *testing.T) {
+	poll := models.NewPoll("What is the best fruit?", []string{"apple", "banana"},
+		models.WithWriteIns())
+	pollID := poll.ID()
+	ballots := []*models.Ballot{
+		models.NewWriteInBallot(pollID, "user1", [][]int{{2}, {0}, {1}}, []string{"Durian"}),
+		models.NewWriteInBallot(pollID, "user2", [][]int{{2}, {1}, {0}}, []string{"  durian "}),
+		models.NewBallot(pollID, "user3", []int{0, 1}),
+		models.NewWriteInBallot(pollID, "user4", [][]int{{2}, {0}, {1}}, []string{"DURIAN"}),
+		models.NewWriteInBallot(pollID, "user5", [][]int{{1}, {0}, {2}}, []string{"kiwi"}),
+	}
+	outcome, err := models.Tabulate(poll, ballots)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	// The merged write-ins come after the poll's own choices
+	if winners := outcome.Winners(); !cmp.Equal(winners, []int{2}) {
+		t.Error("unexpected winners:", winners)
+	}
+	body, err := json.Marshal(outcome)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	var parsed struct {
+		TotalVotes    int    `json:"totalVotes"`
+		WinningVotes  int    `json:"winningVotes"`
+		WinningChoice string `json:"winningChoice"`
+		WriteIns      []struct {
+			Name   string   `json:"name"`
+			Merged []string `json:"merged"`
+		} `json:"writeIns"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		t.Fatal(err.Error())
+	}
+	if parsed.TotalVotes != 5 || parsed.WinningVotes != 3 || parsed.WinningChoice != "Durian" {
+		t.Errorf("unexpected summary: %s", body)
+	}
+	expected := []struct {
+		Name   string   `json:"name"`
+		Merged []string `json:"merged"`
+	}{
+		{"Durian", []string{"Durian", "  durian ", "DURIAN"}},
+		{"kiwi", []string{"kiwi"}},
+	}
+	if !cmp.Equal(parsed.WriteIns, expected) {
+		t.Errorf("unexpected write-ins: %+v", parsed.WriteIns)
+	}
+}